@@ -0,0 +1,4 @@
+// Package merchv1 - gRPC API магазина. merch.pb.go и merch_grpc.pb.go сгенерированы из merch.proto
+package merchv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative merch.proto
@@ -2,21 +2,28 @@ package main
 
 import (
 	"avito_staj_2025/domain"
-	"avito_staj_2025/internal/service/dsn"
+	"avito_staj_2025/internal/config"
+	"flag"
 	"fmt"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"log"
+	"os"
 )
 
-func migrate() (err error) {
-	_ = godotenv.Load()
-	db, err := gorm.Open(postgres.Open(dsn.FromEnv()), &gorm.Config{})
+func migrate(configPath string) (err error) {
+	cfg, err := config.LoadDatabase(configPath)
+	if err != nil {
+		return err
+	}
+	// Создание индексов на больших таблицах может идти дольше statement_timeout приложения
+	cfg.StatementTimeout = 0
+	db, err := gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{})
 	if err != nil {
 		return err
 	}
-	err = db.AutoMigrate(&domain.User{}, &domain.Inventory{}, &domain.Transaction{})
+	err = db.AutoMigrate(domain.Models()...)
 	if err != nil {
 		return err
 	}
@@ -25,7 +32,11 @@ func migrate() (err error) {
 }
 
 func main() {
-	err := migrate()
+	_ = godotenv.Load()
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to YAML config file")
+	flag.Parse()
+
+	err := migrate(*configPath)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -1,55 +1,275 @@
 package main
 
 import (
+	"avito_staj_2025/domain"
 	authController "avito_staj_2025/internal/auth/controller"
 	authRepository "avito_staj_2025/internal/auth/repository"
 	authUsecase "avito_staj_2025/internal/auth/usecase"
+	"avito_staj_2025/internal/config"
+	"avito_staj_2025/internal/grpcapi"
 
 	merchController "avito_staj_2025/internal/merch/controller"
 	merchRepository "avito_staj_2025/internal/merch/repository"
 	merchUsecase "avito_staj_2025/internal/merch/usecase"
+	merchWorker "avito_staj_2025/internal/merch/worker"
+	"avito_staj_2025/internal/service/cache"
+	"avito_staj_2025/internal/service/dbrouter"
+	"avito_staj_2025/internal/service/health"
 	"avito_staj_2025/internal/service/logger"
+	"avito_staj_2025/internal/service/metrics"
 	"avito_staj_2025/internal/service/middleware"
+	"avito_staj_2025/internal/service/openapi"
+	"avito_staj_2025/internal/service/ratelimit"
 	"avito_staj_2025/internal/service/router"
+	"avito_staj_2025/internal/service/tracing"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"github.com/go-redis/redis/v8"
 	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
 func main() {
 	_ = godotenv.Load()
-	db := middleware.DbConnect()
-	jwtToken, err := middleware.NewJwtToken("secret-key")
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to YAML config file")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	db := middleware.DbConnect(cfg.Database)
+	replicas := middleware.DbConnectReplicas(cfg.Database)
+	dbRouter := dbrouter.New(db, replicas, cfg.Database.ReadYourWritesWindow)
+	redisClient := middleware.RedisConnect(cfg.Redis)
+	if sqlDB, err := db.DB(); err == nil {
+		if err := metrics.RegisterDBStats(sqlDB, "postgres"); err != nil {
+			log.Fatalf("Failed to register database metrics: %v", err)
+		}
+	}
+	for i, replica := range replicas {
+		if sqlDB, err := replica.DB(); err == nil {
+			if err := metrics.RegisterDBStats(sqlDB, fmt.Sprintf("postgres_replica_%d", i)); err != nil {
+				log.Fatalf("Failed to register database replica metrics: %v", err)
+			}
+		}
+	}
+	jwtToken, err := middleware.NewJwtToken(cfg.JWT.Secret)
 	if err != nil {
 		log.Fatalf("Failed to create JWT token: %v", err)
 	}
 
-	if err := logger.InitLoggers(); err != nil {
-		log.Fatalf("Failed to initialize loggers: %v", err)
+	accessLogger, err := logger.New(cfg.Logging, cfg.Logging.AccessOutputs)
+	if err != nil {
+		log.Fatalf("Failed to initialize access logger: %v", err)
+	}
+	dbLogger, err := logger.New(cfg.Logging, cfg.Logging.DBOutputs)
+	if err != nil {
+		log.Fatalf("Failed to initialize db logger: %v", err)
 	}
+	// Sync для stdout на части систем возвращает ошибку, терять из-за неё завершение не стоит
 	defer func() {
-		err := logger.SyncLoggers()
+		_ = accessLogger.Sync()
+		_ = dbLogger.Sync()
+	}()
+
+	authRepository := authRepository.NewRoutedAuthRepository(dbRouter, dbLogger)
+	authUseCase := authUsecase.WithTracing(authUsecase.NewAuthUsecase(authRepository, accessLogger))
+	authHandler := authController.NewAuthHandler(authUseCase, jwtToken, accessLogger)
+
+	merchRepo := merchRepository.NewRoutedMerchRepository(dbRouter, dbLogger)
+	if cfg.Cache.Enabled() {
+		store, err := cache.New(cfg.Cache, redisClient)
 		if err != nil {
-			log.Fatalf("Failed to sync loggers: %v", err)
+			log.Fatalf("Failed to create cache: %v", err)
 		}
-	}()
+		merchRepo = merchRepository.WithCache(merchRepo, store, cfg.Cache.TTL, dbLogger)
+	}
+	merchUseCase := merchUsecase.WithTracing(merchUsecase.NewMerchUsecase(merchRepo, cfg.Transfers, accessLogger))
+	merchHandler := merchController.NewMerchHandler(merchUseCase, jwtToken, accessLogger)
 
-	authRepository := authRepository.NewAuthRepository(db)
-	authUseCase := authUsecase.NewAuthUsecase(authRepository)
-	authHandler := authController.NewAuthHandler(authUseCase, jwtToken)
+	// SIGTERM приходит от docker-compose при остановке контейнера
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	merchRepository := merchRepository.NewMerchRepository(db)
-	merchUseCase := merchUsecase.NewMerchUsecase(merchRepository)
-	merchHandler := merchController.NewMerchHandler(merchUseCase, jwtToken)
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		merchWorker.NewPendingTransferWorker(merchUseCase, cfg.Transfers.PendingCheckInterval, accessLogger).Run(workersCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		merchWorker.NewScheduledTransferWorker(merchUseCase, cfg.Transfers.ScheduledCheckInterval, accessLogger).Run(workersCtx)
+	}()
 
-	mainRouter := router.SetUpRoutes(authHandler, merchHandler)
+	spec, err := openapi.Load()
+	if err != nil {
+		log.Fatalf("Failed to load openapi spec: %v", err)
+	}
+	mainRouter := router.SetUpRoutes(authHandler, merchHandler, spec)
+	mainRouter.Use(middleware.MetricsMiddleware)
 	mainRouter.Use(middleware.RequestIDMiddleware)
-	mainRouter.Use(middleware.RateLimitMiddleware)
-	http.Handle("/", middleware.EnableCORS(mainRouter))
-	fmt.Printf("Starting HTTP server on address %s\n", os.Getenv("BACKEND_URL"))
-	if err := http.ListenAndServe(os.Getenv("BACKEND_URL"), nil); err != nil {
-		fmt.Printf("Error on starting server: %s", err)
+	mainRouter.Use(middleware.AccessLogMiddleware(accessLogger, jwtToken))
+	mainRouter.Use(middleware.RecoveryMiddleware(accessLogger))
+	mainRouter.Use(middleware.TracingMiddleware)
+	mainRouter.Use(middleware.DeadlineMiddleware(cfg.Server))
+	limiter, err := ratelimit.New(cfg.RateLimit, redisClient)
+	if err != nil {
+		log.Fatalf("Failed to create rate limiter: %v", err)
+	}
+	rateLimit, err := middleware.RateLimitMiddleware(cfg.RateLimit, limiter, jwtToken, accessLogger)
+	if err != nil {
+		log.Fatalf("Failed to create rate limiter: %v", err)
+	}
+	mainRouter.Use(rateLimit)
+	validateSpec, err := openapi.ValidationMiddleware(spec, cfg.OpenAPI, accessLogger)
+	if err != nil {
+		log.Fatalf("Failed to create openapi validator: %v", err)
+	}
+	mainRouter.Use(validateSpec)
+
+	checks := []health.Check{health.PostgresCheck(db), health.MigrationsCheck(db, domain.Models()...)}
+	for i, replica := range replicas {
+		checks = append(checks, health.PostgresReplicaCheck(i, replica))
+	}
+	if redisClient != nil {
+		checks = append(checks, health.RedisCheck(redisClient))
+	}
+	checker := health.NewChecker(cfg.Server.HealthCheckTimeout, checks...)
+
+	// Проверки здоровья и метрики не проходят через лимитер запросов
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", checker.Liveness)
+	mux.HandleFunc("/readyz", checker.Readiness)
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/", middleware.CORS(cfg.CORS)(mainRouter))
+
+	server := &http.Server{
+		Addr:              cfg.Server.Address,
+		Handler:           mux,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	serverErr := make(chan error, 2)
+	go func() {
+		fmt.Printf("Starting HTTP server on address %s\n", cfg.Server.Address)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled() {
+		listener, err := net.Listen("tcp", cfg.GRPC.Address)
+		if err != nil {
+			log.Fatalf("Failed to listen gRPC address: %v", err)
+		}
+		grpcServer = grpcapi.NewServer(authUseCase, merchUseCase, jwtToken, cfg.Server, accessLogger)
+		go func() {
+			fmt.Printf("Starting gRPC server on address %s\n", cfg.GRPC.Address)
+			if err := grpcServer.Serve(listener); err != nil {
+				serverErr <- err
+			}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		fmt.Println("Shutdown signal received, draining connections")
+	case err := <-serverErr:
+		fmt.Printf("Error on starting server: %s\n", err)
+	}
+	stop()
+
+	checker.SetShuttingDown()
+	if cfg.Server.DrainDelay > 0 {
+		fmt.Printf("Waiting %s for load balancers to notice readiness change\n", cfg.Server.DrainDelay)
+		time.Sleep(cfg.Server.DrainDelay)
+	}
+
+	shutdown(server, grpcServer, cfg.Server.ShutdownTimeout, stopWorkers, &workers, append([]*gorm.DB{db}, replicas...), redisClient, shutdownTracing)
+}
+
+// shutdown останавливает приложение по порядку: сначала перестаём принимать запросы и дожидаемся начатых,
+// затем фоновые обработчики, и только после них закрываем соединения с хранилищами и выгружаем спаны.
+// Логгеры синхронизируются в defer main
+func shutdown(server *http.Server, grpcServer *grpc.Server, timeout time.Duration, stopWorkers context.CancelFunc, workers *sync.WaitGroup, databases []*gorm.DB, redisClient *redis.Client, shutdownTracing func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		fmt.Printf("HTTP server did not drain in %s: %s\n", timeout, err)
+	}
+	if grpcServer != nil {
+		stopGRPC(ctx, grpcServer)
+	}
+
+	stopWorkers()
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-ctx.Done():
+		fmt.Println("Background workers did not stop before shutdown timeout")
+	}
+
+	for _, db := range databases {
+		sqlDB, err := db.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil {
+			fmt.Printf("Failed to close database: %s\n", err)
+		}
+	}
+	if redisClient != nil {
+		if err := redisClient.Close(); err != nil {
+			fmt.Printf("Failed to close redis: %s\n", err)
+		}
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		fmt.Printf("Failed to flush traces: %s\n", err)
+	}
+	fmt.Println("Server stopped")
+}
+
+// stopGRPC дожидается начатых вызовов, а по истечении срока обрывает их
+func stopGRPC(ctx context.Context, grpcServer *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		fmt.Println("gRPC server did not drain before shutdown timeout")
+		grpcServer.Stop()
 	}
 }
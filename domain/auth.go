@@ -7,11 +7,13 @@ type User struct {
 	Username string `gorm:"index:idx_users_username,unique;type:varchar(50);not null;column:username" json:"username"`
 	Password string `gorm:"type:varchar(255);not null;column:password" json:"password"`
 	Coins    int    `gorm:"type:int;default:0;column:coins" json:"coins"`
+	// Растёт при каждом изменении баланса, инвентаря или истории переводов
+	InfoVersion int64 `gorm:"type:bigint;not null;default:0;column:info_version" json:"-"`
 }
 
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string `json:"username" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,max=100"`
 }
 
 type AuthRepository interface {
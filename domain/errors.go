@@ -0,0 +1,14 @@
+package domain
+
+// ErrorResponse - тело любого ответа с ошибкой. RequestID совпадает с заголовком X-Request-ID
+type ErrorResponse struct {
+	Errors    string       `json:"errors"`
+	RequestID string       `json:"requestId,omitempty"`
+	Fields    []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError - ошибка в конкретном поле тела запроса. Field - имя поля в JSON
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
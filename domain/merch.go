@@ -1,6 +1,10 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"github.com/robfig/cron/v3"
+	"time"
+)
 
 var MerchTypes = map[string]int{
 	"t-shirt":    80,
@@ -15,6 +19,23 @@ var MerchTypes = map[string]int{
 	"pink-hoody": 500,
 }
 
+// PriceSchedule - цена товара, действующая в промежутке [StartsAt, EndsAt)
+type PriceSchedule struct {
+	ID        int       `gorm:"primary_key;auto_increment;column:id" json:"id"`
+	ItemName  string    `gorm:"type:varchar(255);column:item_name;not null;index:idx_price_schedule_item_period" json:"itemName"`
+	Price     int       `gorm:"type:int;column:price;not null" json:"price"`
+	StartsAt  time.Time `gorm:"column:starts_at;not null;index:idx_price_schedule_item_period" json:"startsAt"`
+	EndsAt    time.Time `gorm:"column:ends_at;not null;index:idx_price_schedule_item_period" json:"endsAt"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+type CatalogItem struct {
+	Name       string     `json:"name"`
+	Price      int        `json:"price"`
+	BasePrice  int        `json:"basePrice"`
+	SaleEndsAt *time.Time `json:"saleEndsAt,omitempty"`
+}
+
 type Inventory struct {
 	ID         int    `gorm:"primary_key;auto_increment;column:id" json:"id"`
 	OwnerID    string `gorm:"column:owner_id;not null;index:idx_owner_item,unique" json:"ownerID"`
@@ -23,13 +44,59 @@ type Inventory struct {
 	User       User   `gorm:"foreignkey:OwnerID;references:UUID" json:"-"`
 }
 
+const (
+	DiscountPercent = "percent"
+	DiscountFixed   = "fixed"
+)
+
+// PromoCode - скидка на покупку. Пустой ItemName означает скидку на любой товар, MaxUses = 0 - без ограничений
+type PromoCode struct {
+	Code          string     `gorm:"type:varchar(50);primaryKey;column:code" json:"code"`
+	DiscountType  string     `gorm:"type:varchar(20);column:discount_type;not null" json:"discountType"`
+	DiscountValue int        `gorm:"type:int;column:discount_value;not null" json:"discountValue"`
+	ItemName      string     `gorm:"type:varchar(255);column:item_name" json:"itemName,omitempty"`
+	MaxUses       int        `gorm:"type:int;column:max_uses;not null;default:0" json:"maxUses"`
+	UsedCount     int        `gorm:"type:int;column:used_count;not null;default:0" json:"usedCount"`
+	ExpiresAt     *time.Time `gorm:"column:expires_at" json:"expiresAt,omitempty"`
+	CreatedAt     time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// DiscountedPrice возвращает цену товара с учётом скидки, но не меньше нуля
+func (p *PromoCode) DiscountedPrice(price int) int {
+	discounted := price
+	switch p.DiscountType {
+	case DiscountPercent:
+		discounted = price - price*p.DiscountValue/100
+	case DiscountFixed:
+		discounted = price - p.DiscountValue
+	}
+	if discounted < 0 {
+		return 0
+	}
+	return discounted
+}
+
+type Purchase struct {
+	UUID      string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid();column:uuid" json:"id"`
+	UserID    string    `gorm:"column:user_id;not null;index:idx_purchases_user" json:"userID"`
+	ItemName  string    `gorm:"type:varchar(255);column:item_name;not null" json:"itemName"`
+	BasePrice int       `gorm:"type:int;column:base_price;not null" json:"basePrice"`
+	Price     int       `gorm:"type:int;column:price;not null" json:"price"`
+	PromoCode string    `gorm:"type:varchar(50);column:promo_code" json:"promoCode,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	User      User      `gorm:"foreignkey:UserID;references:UUID" json:"-"`
+}
+
 type Transaction struct {
 	UUID       string `gorm:"type:uuid;primaryKey;default:gen_random_uuid();column:uuid" json:"id"`
-	SenderID   string `gorm:"column:sender_id;not null" json:"senderID"`
-	ReceiverID string `gorm:"column:receiver_id;not null" json:"receiverID"`
+	SenderID   string `gorm:"column:sender_id;not null;index:idx_transactions_sender_created" json:"senderID"`
+	ReceiverID string `gorm:"column:receiver_id;not null;index:idx_transactions_receiver_created" json:"receiverID"`
 	Amount     int    `gorm:"type:int;column:amount;not null" json:"amount"`
-	Sender     User   `gorm:"foreignkey:SenderID;references:UUID" json:"-"`
-	Receiver   User   `gorm:"foreignkey:ReceiverID;references:UUID" json:"-"`
+	// У переводов, созданных до появления колонки, время неизвестно: NULL не попадает в рейтинги за неделю и месяц.
+	// idx_transactions_created обслуживает выборку окна рейтинга по времени
+	CreatedAt *time.Time `gorm:"column:created_at;autoCreateTime;index:idx_transactions_created;index:idx_transactions_sender_created;index:idx_transactions_receiver_created" json:"createdAt,omitempty"`
+	Sender    User       `gorm:"foreignkey:SenderID;references:UUID" json:"-"`
+	Receiver  User       `gorm:"foreignkey:ReceiverID;references:UUID" json:"-"`
 }
 
 type TransactionWithUsers struct {
@@ -42,6 +109,8 @@ type UserInformationResponse struct {
 	Coins       int                 `gorm:"type:int;default:0;column:coins" json:"coins"`
 	Inventory   []InventoryResponse `gorm:"foreignkey:InventoryID;references:ID" json:"inventory"`
 	CoinHistory CoinHistory         `gorm:"foreignkey:CoinHistoryID;references:ID" json:"coinHistory"`
+	// Версия данных пользователя (users.info_version), из которой собран ответ. Клиенту отдаётся только в ETag
+	Version int64 `gorm:"-" json:"-"`
 }
 
 type InventoryResponse struct {
@@ -65,12 +134,181 @@ type SentResponse struct {
 }
 
 type SentRequest struct {
+	ToUser  string `json:"toUser" validate:"required,max=100"`
+	Amount  int    `json:"amount" validate:"min=1"`
+	Pending bool   `json:"pending"`
+}
+
+type SendCoinsResponse struct {
+	TransferID string `json:"transferId"`
+}
+
+const (
+	TransferStatusPending  = "pending"
+	TransferStatusAccepted = "accepted"
+	TransferStatusDeclined = "declined"
+	TransferStatusExpired  = "expired"
+	// Прямой перевод без подтверждения получателя
+	TransferStatusCompleted = "completed"
+)
+
+// PendingTransfer - перевод, монеты которого удерживаются до решения получателя
+type PendingTransfer struct {
+	UUID       string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid();column:uuid" json:"id"`
+	SenderID   string    `gorm:"column:sender_id;not null;index:idx_pending_sender" json:"senderID"`
+	ReceiverID string    `gorm:"column:receiver_id;not null;index:idx_pending_receiver" json:"receiverID"`
+	Amount     int       `gorm:"type:int;column:amount;not null" json:"amount"`
+	Status     string    `gorm:"type:varchar(20);column:status;not null;default:pending;index:idx_pending_status_expires" json:"status"`
+	ExpiresAt  time.Time `gorm:"column:expires_at;not null;index:idx_pending_status_expires" json:"expiresAt"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	Sender     User      `gorm:"foreignkey:SenderID;references:UUID" json:"-"`
+	Receiver   User      `gorm:"foreignkey:ReceiverID;references:UUID" json:"-"`
+}
+
+type PendingTransferWithUsers struct {
+	UUID         string
+	SenderID     string
+	SenderName   string
+	ReceiverName string
+	Amount       int
+	ExpiresAt    time.Time
+}
+
+type PendingTransferResponse struct {
+	ID        string    `json:"id"`
+	FromUser  string    `json:"fromUser"`
+	ToUser    string    `json:"toUser"`
+	Amount    int       `json:"amount"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type PendingTransfersResponse struct {
+	Incoming []PendingTransferResponse `json:"incoming"`
+	Outgoing []PendingTransferResponse `json:"outgoing"`
+}
+
+// PurchaseRequest - тело POST /api/v2/purchases
+type PurchaseRequest struct {
+	Item      string `json:"item" validate:"required,max=100"`
+	PromoCode string `json:"promoCode" validate:"max=50"`
+}
+
+// TransferResponse - ответ POST /api/v2/transfers. ID - запись о переводе или ожидающий перевод при Status = pending
+type TransferResponse struct {
+	ID     string `json:"id"`
 	ToUser string `json:"toUser"`
 	Amount int    `json:"amount"`
+	Status string `json:"status"`
+}
+
+const (
+	ScheduleStatusActive    = "active"
+	ScheduleStatusCompleted = "completed"
+	ScheduleStatusFailed    = "failed"
+	ScheduleStatusCancelled = "cancelled"
+)
+
+// ScheduledTransfer - разовый (Schedule пустой) или повторяющийся по cron-расписанию перевод
+type ScheduledTransfer struct {
+	UUID             string     `gorm:"type:uuid;primaryKey;default:gen_random_uuid();column:uuid" json:"id"`
+	SenderID         string     `gorm:"column:sender_id;not null;index:idx_scheduled_sender" json:"senderID"`
+	ReceiverUsername string     `gorm:"type:varchar(50);column:receiver_username;not null" json:"toUser"`
+	Amount           int        `gorm:"type:int;column:amount;not null" json:"amount"`
+	Schedule         string     `gorm:"type:varchar(100);column:schedule" json:"schedule,omitempty"`
+	Status           string     `gorm:"type:varchar(20);column:status;not null;default:active;index:idx_scheduled_due" json:"status"`
+	NextRunAt        time.Time  `gorm:"column:next_run_at;not null;index:idx_scheduled_due" json:"nextRunAt"`
+	LastRunAt        *time.Time `gorm:"column:last_run_at" json:"lastRunAt,omitempty"`
+	LastError        string     `gorm:"type:varchar(255);column:last_error" json:"lastError,omitempty"`
+	LockedUntil      *time.Time `gorm:"column:locked_until" json:"-"`
+	CreatedAt        time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	Sender           User       `gorm:"foreignkey:SenderID;references:UUID" json:"-"`
+}
+
+// RecordRun записывает итог запуска: разовый перевод завершается, повторяющийся переносится на следующее
+// время по расписанию. Пропущенные во время простоя запуски не навёрстываются
+func (t *ScheduledTransfer) RecordRun(ranAt time.Time, runErr error) {
+	t.LastRunAt = &ranAt
+	t.LastError = ""
+	if runErr != nil {
+		t.LastError = runErr.Error()
+	}
+
+	if t.Schedule == "" {
+		t.Status = ScheduleStatusCompleted
+		if runErr != nil {
+			t.Status = ScheduleStatusFailed
+		}
+		return
+	}
+	schedule, err := cron.ParseStandard(t.Schedule)
+	if err != nil {
+		t.Status = ScheduleStatusFailed
+		t.LastError = "invalid schedule"
+		return
+	}
+	t.NextRunAt = schedule.Next(ranAt)
+}
+
+const (
+	ScheduledRunDone    = "ran"
+	ScheduledRunSkipped = "skipped"
+	ScheduledRunFailed  = "failed"
+)
+
+// ScheduledRunResult - итог одного запуска перевода по расписанию. Transaction заполнена при Outcome = ran,
+// Error - причина неудачи этого запуска при Outcome = failed
+type ScheduledRunResult struct {
+	Outcome     string
+	Transaction Transaction
+	Error       string
+}
+
+type ScheduleTransferRequest struct {
+	ToUser   string     `json:"toUser" validate:"required,max=100"`
+	Amount   int        `json:"amount" validate:"min=1"`
+	RunAt    *time.Time `json:"runAt"`
+	Schedule string     `json:"schedule" validate:"max=100"`
+}
+
+const (
+	LeaderboardReceived = "received"
+	LeaderboardThanked  = "thanked"
+
+	LeaderboardWeek    = "week"
+	LeaderboardMonth   = "month"
+	LeaderboardAllTime = "all"
+)
+
+type LeaderboardEntry struct {
+	Rank     int    `gorm:"-" json:"rank"`
+	Username string `gorm:"column:username" json:"username"`
+	Score    int    `gorm:"column:score" json:"score"`
+}
+
+type LeaderboardResponse struct {
+	Category string             `json:"category"`
+	Window   string             `json:"window"`
+	Entries  []LeaderboardEntry `json:"entries"`
 }
 
 type MerchRepository interface {
-	GetUserMerchInformation(ctx context.Context, userID string) (UserInformationResponse, error)
-	SendCoins(ctx context.Context, senderID string, receiverID string, amount int) error
-	BuyItem(ctx context.Context, userID string, itemName string, itemCost int) error
+	// minVersion - версия данных, уже известная вызывающему, или 0. Кэш не отдаёт записи старше неё
+	GetUserMerchInformation(ctx context.Context, userID string, minVersion int64) (UserInformationResponse, error)
+	GetUserInfoVersion(ctx context.Context, userID string) (int64, error)
+	SendCoins(ctx context.Context, senderID string, receiverID string, amount int) (Transaction, error)
+	// basePrice - цена по каталогу, price - действующая цена с учётом распродажи, к ней применяется промокод
+	BuyItem(ctx context.Context, userID string, itemName string, basePrice int, price int, promoCode string) (Purchase, error)
+	CreatePendingTransfer(ctx context.Context, senderID string, receiverUsername string, amount int, expiresAt time.Time) (string, error)
+	GetPendingTransfers(ctx context.Context, userID string) (PendingTransfersResponse, error)
+	AcceptPendingTransfer(ctx context.Context, userID string, transferID string) (Transaction, error)
+	DeclinePendingTransfer(ctx context.Context, userID string, transferID string) (PendingTransfer, error)
+	ExpirePendingTransfers(ctx context.Context, now time.Time) ([]PendingTransfer, error)
+	CreateScheduledTransfer(ctx context.Context, transfer *ScheduledTransfer) error
+	GetScheduledTransfers(ctx context.Context, senderID string) ([]ScheduledTransfer, error)
+	CancelScheduledTransfer(ctx context.Context, senderID string, transferID string) error
+	ClaimDueScheduledTransfers(ctx context.Context, now time.Time, lease time.Duration) ([]ScheduledTransfer, error)
+	RunScheduledTransfer(ctx context.Context, transfer *ScheduledTransfer, ranAt time.Time) (ScheduledRunResult, error)
+	GetActivePriceSchedule(ctx context.Context, itemName string, now time.Time) (*PriceSchedule, error)
+	GetActivePriceSchedules(ctx context.Context, now time.Time) ([]PriceSchedule, error)
+	GetLeaderboard(ctx context.Context, category string, since *time.Time, limit int) ([]LeaderboardEntry, error)
 }
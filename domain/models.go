@@ -0,0 +1,8 @@
+package domain
+
+// Models возвращает все модели, таблицы которых создаёт мигратор
+func Models() []interface{} {
+	return []interface{}{
+		&User{}, &Inventory{}, &Transaction{}, &PendingTransfer{}, &ScheduledTransfer{}, &PromoCode{}, &Purchase{}, &PriceSchedule{},
+	}
+}
@@ -3,8 +3,10 @@ package controller
 import (
 	"avito_staj_2025/domain"
 	"avito_staj_2025/internal/auth/usecase"
+	"avito_staj_2025/internal/service/binding"
 	"avito_staj_2025/internal/service/logger"
 	"avito_staj_2025/internal/service/middleware"
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/microcosm-cc/bluemonday"
@@ -16,36 +18,29 @@ import (
 type AuthHandler struct {
 	usecase  usecase.AuthUsecase
 	jwtToken middleware.JwtTokenService
+	logger   *zap.Logger
 }
 
-func NewAuthHandler(usecase usecase.AuthUsecase, jwtToken middleware.JwtTokenService) *AuthHandler {
+func NewAuthHandler(usecase usecase.AuthUsecase, jwtToken middleware.JwtTokenService, logger *zap.Logger) *AuthHandler {
 	return &AuthHandler{
 		usecase:  usecase,
 		jwtToken: jwtToken,
+		logger:   logger,
 	}
 }
 
 func (h *AuthHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
-	start := time.Now()
-	requestID := middleware.GetRequestID(r.Context())
-	ctx, cancel := middleware.WithTimeout(r.Context())
-	defer cancel()
-
-	logger.AccessLogger.Info("Received LoginUser request",
-		zap.String("request_id", requestID),
-		zap.String("method", r.Method),
-		zap.String("url", r.URL.String()),
-	)
+	ctx := r.Context()
 
 	authHeader := r.Header.Get("JWT-Token")
 	if authHeader != "" {
-		h.handleError(w, errors.New("jwt_token already exists"), requestID)
+		h.handleError(ctx, w, errors.New("jwt_token already exists"))
 		return
 	}
 
 	var creds domain.LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
-		h.handleError(w, err, requestID)
+	if err := binding.JSON(w, r, &creds); err != nil {
+		h.handleError(ctx, w, err)
 		return
 	}
 
@@ -57,14 +52,14 @@ func (h *AuthHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 
 	userID, err := h.usecase.LoginUser(ctx, creds.Username, creds.Password)
 	if err != nil {
-		h.handleError(w, err, requestID)
+		h.handleError(ctx, w, err)
 		return
 	}
 
 	tokenExpTime := time.Now().Add(24 * time.Hour).Unix()
 	jwtToken, err := h.jwtToken.Create(userID, tokenExpTime)
 	if err != nil {
-		h.handleError(w, err, requestID)
+		h.handleError(ctx, w, err)
 		return
 	}
 
@@ -78,40 +73,42 @@ func (h *AuthHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	duration := time.Since(start)
-	logger.AccessLogger.Info("Completed LoginUser request",
-		zap.String("request_id", requestID),
-		zap.Duration("duration", duration),
-		zap.Int("status", http.StatusOK),
-	)
 }
 
-func (h *AuthHandler) handleError(w http.ResponseWriter, err error, requestID string) {
-	logger.AccessLogger.Error("Handling error",
-		zap.String("request_id", requestID),
+func (h *AuthHandler) handleError(ctx context.Context, w http.ResponseWriter, err error) {
+	log := logger.FromContext(ctx, h.logger)
+	requestID := middleware.GetRequestID(ctx)
+	log.Error("Handling error",
 		zap.Error(err),
 	)
 
 	w.Header().Set("Content-Type", "application/json")
-	errorResponse := map[string]string{"errors": err.Error()}
-
-	switch err.Error() {
-	case "not correct username", "not correct password",
-		"jwt_token already exists", "Input contains invalid characters",
-		"Input exceeds character limit":
-		w.WriteHeader(http.StatusBadRequest)
-	case "invalid credentials":
-		w.WriteHeader(http.StatusUnauthorized)
-	case "failed to generate error response":
-		w.WriteHeader(http.StatusInternalServerError)
-	default:
-		w.WriteHeader(http.StatusInternalServerError)
+	errorResponse := domain.ErrorResponse{Errors: err.Error(), RequestID: requestID}
+
+	var bindErr *binding.Error
+	if status, message := middleware.TimeoutStatus(ctx, err); status != 0 {
+		errorResponse.Errors = message
+		w.WriteHeader(status)
+	} else if errors.As(err, &bindErr) {
+		errorResponse.Fields = bindErr.Fields
+		w.WriteHeader(bindErr.Status)
+	} else {
+		switch err.Error() {
+		case "not correct username", "not correct password",
+			"jwt_token already exists", "Input contains invalid characters",
+			"Input exceeds character limit":
+			w.WriteHeader(http.StatusBadRequest)
+		case "invalid credentials":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "failed to generate error response":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
 	}
 
 	if jsonErr := json.NewEncoder(w).Encode(errorResponse); jsonErr != nil {
-		logger.AccessLogger.Error("Failed to encode error response",
-			zap.String("request_id", requestID),
+		log.Error("Failed to encode error response",
 			zap.Error(jsonErr),
 		)
 		http.Error(w, jsonErr.Error(), http.StatusInternalServerError)
@@ -3,22 +3,18 @@ package controller
 import (
 	"avito_staj_2025/domain"
 	"avito_staj_2025/internal/auth/mocks"
-	"avito_staj_2025/internal/service/logger"
 	"bytes"
 	"encoding/json"
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
-	"go.uber.org/zap"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 )
 
 func TestLoginUser(t *testing.T) {
-	logger.AccessLogger = zap.NewNop()
-
 	t.Run("Success - Valid Credentials", func(t *testing.T) {
 		mockUsecase := new(mocks.MockAuthUsecase)
 		mockJWT := new(mocks.MockJwtTokenService)
@@ -99,6 +95,9 @@ func TestLoginUser(t *testing.T) {
 
 func createTestRequest(method, url string, body []byte) (*http.Request, *httptest.ResponseRecorder) {
 	r := httptest.NewRequest(method, url, bytes.NewReader(body))
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
 	w := httptest.NewRecorder()
 	return r, w
 }
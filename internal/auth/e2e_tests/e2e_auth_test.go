@@ -5,15 +5,17 @@ import (
 	auth "avito_staj_2025/internal/auth/controller"
 	authRepository "avito_staj_2025/internal/auth/repository"
 	authUsecase "avito_staj_2025/internal/auth/usecase"
+	"avito_staj_2025/internal/config"
 	dsn2 "avito_staj_2025/internal/service/dsn"
-	"avito_staj_2025/internal/service/logger"
 	"avito_staj_2025/internal/service/middleware"
+	"avito_staj_2025/internal/service/openapi"
 	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"net/http"
@@ -23,6 +25,18 @@ import (
 	"time"
 )
 
+// newContractRouter проверяет запросы и ответы по openapi.yaml, чтобы расхождение спецификации и кода ломало тесты
+func newContractRouter(t *testing.T) *mux.Router {
+	spec, err := openapi.Load()
+	assert.NoError(t, err)
+	validate, err := openapi.ValidationMiddleware(spec, config.OpenAPIConfig{ValidateRequests: true, ValidateResponses: true}, zap.NewNop())
+	assert.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.Use(validate)
+	return router
+}
+
 func createDatabaseIfNotExists() error {
 	host := os.Getenv("DB_HOST_TEST")
 	port := os.Getenv("DB_PORT_TEST")
@@ -88,22 +102,15 @@ func TestLoginUserE2E(t *testing.T) {
 	jwtToken, err := middleware.NewJwtToken("secret-key")
 	assert.NoError(t, err)
 
-	err = logger.InitLoggers()
-	assert.NoError(t, err)
-	defer func() {
-		err := logger.SyncLoggers()
-		assert.NoError(t, err)
-	}()
-
 	username := fmt.Sprintf("user_%d", time.Now().Unix())
 	password := "test_password"
 	createTestUser(t, db, username, password)
 
-	authRepo := authRepository.NewAuthRepository(db)
-	authUC := authUsecase.NewAuthUsecase(authRepo)
-	authHandler := auth.NewAuthHandler(authUC, jwtToken)
+	authRepo := authRepository.NewAuthRepository(db, zap.NewNop())
+	authUC := authUsecase.NewAuthUsecase(authRepo, zap.NewNop())
+	authHandler := auth.NewAuthHandler(authUC, jwtToken, zap.NewNop())
 
-	router := mux.NewRouter()
+	router := newContractRouter(t)
 	api := "/api"
 
 	router.HandleFunc(api+"/auth", authHandler.LoginUser).Methods("POST")
@@ -147,21 +154,14 @@ func TestLoginUserFirstTimeE2E(t *testing.T) {
 	jwtToken, err := middleware.NewJwtToken("secret-key")
 	assert.NoError(t, err)
 
-	err = logger.InitLoggers()
-	assert.NoError(t, err)
-	defer func() {
-		err := logger.SyncLoggers()
-		assert.NoError(t, err)
-	}()
-
 	username := fmt.Sprintf("user_%d", time.Now().Unix())
 	password := "test_password"
 
-	authRepo := authRepository.NewAuthRepository(db)
-	authUC := authUsecase.NewAuthUsecase(authRepo)
-	authHandler := auth.NewAuthHandler(authUC, jwtToken)
+	authRepo := authRepository.NewAuthRepository(db, zap.NewNop())
+	authUC := authUsecase.NewAuthUsecase(authRepo, zap.NewNop())
+	authHandler := auth.NewAuthHandler(authUC, jwtToken, zap.NewNop())
 
-	router := mux.NewRouter()
+	router := newContractRouter(t)
 	api := "/api"
 
 	router.HandleFunc(api+"/auth", authHandler.LoginUser).Methods("POST")
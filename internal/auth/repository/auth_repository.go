@@ -2,43 +2,54 @@ package repository
 
 import (
 	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/service/dbrouter"
 	"avito_staj_2025/internal/service/logger"
-	"avito_staj_2025/internal/service/middleware"
 	"context"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 type authRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	router *dbrouter.Router
+	logger *zap.Logger
 }
 
-func NewAuthRepository(db *gorm.DB) domain.AuthRepository {
+func NewAuthRepository(db *gorm.DB, logger *zap.Logger) domain.AuthRepository {
+	return NewRoutedAuthRepository(dbrouter.New(db, nil, 0), logger)
+}
+
+// NewRoutedAuthRepository отмечает созданных пользователей в роутере, чтобы первый /api/info
+// после регистрации не ушёл в реплику, где пользователя ещё нет
+func NewRoutedAuthRepository(router *dbrouter.Router, logger *zap.Logger) domain.AuthRepository {
 	return &authRepository{
-		db: db,
+		db:     router.Primary(),
+		router: router,
+		logger: logger,
 	}
 }
 
 func (r *authRepository) AuthUser(ctx context.Context, username string, password string) (*domain.User, error) {
-	requestID := middleware.GetRequestID(ctx)
+	log := logger.FromContext(ctx, r.logger)
 	var user domain.User
-	logger.DBLogger.Info("AuthUser called", zap.String("request_id", requestID), zap.String("username", username))
-	if err := r.db.Select("uuid, username, password").Where("username = ?", username).First(&user).Error; err != nil {
+	log.Info("AuthUser called", zap.String("username", username))
+	if err := r.db.WithContext(ctx).Select("uuid, username, password").Where("username = ?", username).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			user.Username = username
 			user.Password = password
 			user.Coins = 1000
-			err = r.db.Create(&user).Error
+			err = r.db.WithContext(ctx).Create(&user).Error
 			if err != nil {
-				logger.DBLogger.Error("Error creating user", zap.String("username", username), zap.Error(err))
+				log.Error("Error creating user", zap.String("username", username), zap.Error(err))
 				return nil, err
 			}
-			logger.DBLogger.Info("Successfully create user", zap.String("request_id", requestID), zap.String("username", username))
+			r.router.MarkWritten(user.UUID)
+			log.Info("Successfully create user", zap.String("username", username))
 			return &domain.User{UUID: user.UUID, Username: username, Password: ""}, nil
 		}
-		logger.DBLogger.Error("Error getting user", zap.String("request_id", requestID), zap.String("username", username), zap.Error(err))
+		log.Error("Error getting user", zap.String("username", username), zap.Error(err))
 		return nil, err
 	}
-	logger.DBLogger.Info("Successfully auth user", zap.String("request_id", requestID), zap.String("username", username))
+	log.Info("Successfully auth user", zap.String("username", username))
 	return &domain.User{UUID: user.UUID, Username: user.Username, Password: user.Password}, nil
 }
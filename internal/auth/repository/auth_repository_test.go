@@ -1,7 +1,6 @@
 package repository
 
 import (
-	"avito_staj_2025/internal/service/logger"
 	"context"
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
@@ -15,7 +14,6 @@ import (
 )
 
 func TestAuthUser(t *testing.T) {
-	logger.DBLogger = zap.NewNop()
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
@@ -25,7 +23,7 @@ func TestAuthUser(t *testing.T) {
 	}), &gorm.Config{})
 	require.NoError(t, err)
 
-	authRepo := NewAuthRepository(gormDB)
+	authRepo := NewAuthRepository(gormDB, zap.NewNop())
 	ctx := context.Background()
 
 	t.Run("Success - Existing User", func(t *testing.T) {
@@ -56,8 +54,8 @@ func TestAuthUser(t *testing.T) {
 			WithArgs(username, 1).
 			WillReturnError(gorm.ErrRecordNotFound)
 		mock.ExpectBegin()
-		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users" ("username","password","coins") VALUES ($1,$2,$3) RETURNING "uuid"`)).
-			WithArgs("newUser", "hashedPassword", 1000).
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users" ("username","password","coins","info_version") VALUES ($1,$2,$3,$4) RETURNING "uuid"`)).
+			WithArgs("newUser", "hashedPassword", 1000, 0).
 			WillReturnRows(sqlmock.NewRows([]string{"uuid"}).AddRow("some-uuid"))
 		mock.ExpectCommit()
 		user, err := authRepo.AuthUser(ctx, username, password)
@@ -77,8 +75,8 @@ func TestAuthUser(t *testing.T) {
 			WillReturnError(gorm.ErrRecordNotFound)
 
 		mock.ExpectBegin()
-		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users" ("username","password","coins") VALUES ($1,$2,$3) RETURNING "uuid"`)).
-			WithArgs(username, password, 1000).
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users" ("username","password","coins","info_version") VALUES ($1,$2,$3,$4) RETURNING "uuid"`)).
+			WithArgs(username, password, 1000, 0).
 			WillReturnError(errors.New("failed to create user"))
 		mock.ExpectRollback()
 		user, err := authRepo.AuthUser(ctx, username, password)
@@ -3,7 +3,6 @@ package usecase
 import (
 	"avito_staj_2025/domain"
 	"avito_staj_2025/internal/service/logger"
-	"avito_staj_2025/internal/service/middleware"
 	"avito_staj_2025/internal/service/validation"
 	"context"
 	"errors"
@@ -16,27 +15,29 @@ type AuthUsecase interface {
 
 type authUsecase struct {
 	authRepository domain.AuthRepository
+	logger         *zap.Logger
 }
 
-func NewAuthUsecase(authRepository domain.AuthRepository) AuthUsecase {
+func NewAuthUsecase(authRepository domain.AuthRepository, logger *zap.Logger) AuthUsecase {
 	return &authUsecase{
 		authRepository: authRepository,
+		logger:         logger,
 	}
 }
 
 func (uc *authUsecase) LoginUser(ctx context.Context, username string, password string) (string, error) {
-	requestID := middleware.GetRequestID(ctx)
+	log := logger.FromContext(ctx, uc.logger)
 	const maxLen = 100
 	if len(username) > maxLen || len(password) > maxLen {
-		logger.AccessLogger.Warn("Input exceeds character limit", zap.String("request_id", requestID))
+		log.Warn("Input exceeds character limit")
 		return "", errors.New("Input exceeds character limit")
 	}
 	if !validation.ValidateLogin(username) {
-		logger.AccessLogger.Warn("not correct username", zap.String("request_id", requestID))
+		log.Warn("not correct username")
 		return "", errors.New("not correct username")
 	}
 	if !validation.ValidatePassword(password) {
-		logger.AccessLogger.Warn("not corrects password", zap.String("request_id", requestID))
+		log.Warn("not corrects password")
 		return "", errors.New("not correct password")
 	}
 
@@ -3,7 +3,6 @@ package usecase
 import (
 	"avito_staj_2025/domain"
 	"avito_staj_2025/internal/auth/mocks"
-	"avito_staj_2025/internal/service/logger"
 	"context"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -13,10 +12,8 @@ import (
 )
 
 func TestLoginUser(t *testing.T) {
-	logger.AccessLogger = zap.NewNop()
-
 	mockRepo := new(mocks.MockAuthRepository)
-	authUC := NewAuthUsecase(mockRepo)
+	authUC := NewAuthUsecase(mockRepo, zap.NewNop())
 
 	ctx := context.Background()
 	validUsername := "validUser"
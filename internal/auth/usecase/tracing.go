@@ -0,0 +1,23 @@
+package usecase
+
+import (
+	"avito_staj_2025/internal/service/tracing"
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tracedAuthUsecase оборачивает AuthUsecase в спаны. Пароль в атрибуты не попадает
+type tracedAuthUsecase struct {
+	next AuthUsecase
+}
+
+func WithTracing(next AuthUsecase) AuthUsecase {
+	return &tracedAuthUsecase{next: next}
+}
+
+func (uc *tracedAuthUsecase) LoginUser(ctx context.Context, username string, password string) (string, error) {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.LoginUser", attribute.String("username", username))
+	token, err := uc.next.LoginUser(ctx, username, password)
+	tracing.End(span, err)
+	return token, err
+}
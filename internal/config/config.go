@@ -0,0 +1,661 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config - настройки приложения. Порядок применения: значения по умолчанию, YAML-файл, переменные окружения
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Redis     RedisConfig     `yaml:"redis"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	RateLimit RateLimitConfig `yaml:"rateLimit"`
+	Transfers TransfersConfig `yaml:"transfers"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Logging   LoggingConfig   `yaml:"logging"`
+	CORS      CORSConfig      `yaml:"cors"`
+	OpenAPI   OpenAPIConfig   `yaml:"openapi"`
+	GRPC      GRPCConfig      `yaml:"grpc"`
+	Cache     CacheConfig     `yaml:"cache"`
+}
+
+type ServerConfig struct {
+	Address           string        `yaml:"address"`
+	ReadTimeout       time.Duration `yaml:"readTimeout"`
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout"`
+	WriteTimeout      time.Duration `yaml:"writeTimeout"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout"`
+	// Сколько /readyz отвечает ошибкой до остановки приёма соединений, чтобы балансировщик успел убрать экземпляр
+	DrainDelay time.Duration `yaml:"drainDelay"`
+	// Сколько ждать завершения начатых запросов после SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	// Ограничение на время проверки зависимостей в /readyz
+	HealthCheckTimeout time.Duration `yaml:"healthCheckTimeout"`
+	// Срок обработки запроса, после которого клиент получает 504
+	RequestTimeout time.Duration `yaml:"requestTimeout"`
+	// Отдельные сроки по шаблонам маршрутов, например /api/leaderboard
+	RouteTimeouts map[string]time.Duration `yaml:"routeTimeouts"`
+}
+
+type DatabaseConfig struct {
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	Name            string        `yaml:"name"`
+	SSLMode         string        `yaml:"sslMode"`
+	MaxOpenConns    int           `yaml:"maxOpenConns"`
+	MaxIdleConns    int           `yaml:"maxIdleConns"`
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"connMaxIdleTime"`
+	// statement_timeout сессий Postgres, 0 - без ограничения. Страхует запросы без дедлайна, например из фоновых обработчиков
+	StatementTimeout time.Duration `yaml:"statementTimeout"`
+	// Строки подключения к репликам для чтения в формате key=value, например "host=replica1 port=5432 user=... dbname=...".
+	// Пустой список - все запросы идут в основную БД
+	Replicas []string `yaml:"replicas"`
+	// Сколько после изменения баланса пользователя его данные читаются из основной БД, а не из отстающих реплик.
+	// 0 отключает
+	ReadYourWritesWindow time.Duration `yaml:"readYourWritesWindow"`
+}
+
+// RedisConfig - необязательное подключение. Пустой адрес отключает Redis
+type RedisConfig struct {
+	Address  string `yaml:"address"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+}
+
+func (c RedisConfig) Enabled() bool {
+	return c.Address != ""
+}
+
+type JWTConfig struct {
+	Secret string `yaml:"secret"`
+}
+
+// Хранилища состояния лимитера запросов
+const (
+	RateLimitBackendMemory = "memory"
+	RateLimitBackendRedis  = "redis"
+)
+
+// RateLimitConfig - бюджеты на одного клиента: пользователя из JWT или, без токена, IP-адрес
+type RateLimitConfig struct {
+	// memory - у каждой реплики свои счётчики, redis - общие для всех реплик
+	Backend string `yaml:"backend"`
+	// Бюджет для маршрутов без собственной настройки
+	RequestsPerSecond float64 `yaml:"requestsPerSecond"`
+	Burst             int     `yaml:"burst"`
+	// Отдельные бюджеты по шаблонам маршрутов, например /api/auth
+	Routes map[string]RateLimitBudget `yaml:"routes"`
+	// Сколько хранить состояние клиента после последнего запроса
+	EntryTTL time.Duration `yaml:"entryTTL"`
+	// Адреса и подсети прокси, которым доверяем X-Forwarded-For
+	TrustedProxies []string `yaml:"trustedProxies"`
+}
+
+type RateLimitBudget struct {
+	RequestsPerSecond float64 `yaml:"requestsPerSecond"`
+	Burst             int     `yaml:"burst"`
+}
+
+type TransfersConfig struct {
+	// Время, в течение которого получатель может принять перевод
+	PendingTTL time.Duration `yaml:"pendingTTL"`
+	// Как часто возвращать монеты по просроченным переводам
+	PendingCheckInterval time.Duration `yaml:"pendingCheckInterval"`
+	// Как часто проверять наступившие запланированные переводы
+	ScheduledCheckInterval time.Duration `yaml:"scheduledCheckInterval"`
+	// Время, на которое экземпляр приложения захватывает запланированный перевод
+	ScheduledLease time.Duration `yaml:"scheduledLease"`
+}
+
+// Экспортёры трассировок
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
+type TracingConfig struct {
+	Exporter string `yaml:"exporter"`
+	// Адрес OTLP/HTTP коллектора, например otel-collector:4318
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	ServiceName string  `yaml:"serviceName"`
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+// Форматы логов
+const (
+	LogEncodingJSON    = "json"
+	LogEncodingConsole = "console"
+)
+
+type LoggingConfig struct {
+	// debug | info | warn | error
+	Level    string `yaml:"level"`
+	Encoding string `yaml:"encoding"`
+	// Куда писать логи запросов и логи репозиториев: stdout, stderr или путь к файлу
+	AccessOutputs []string `yaml:"accessOutputs"`
+	DBOutputs     []string `yaml:"dbOutputs"`
+	// За секунду пишутся первые SamplingInitial одинаковых записей, дальше - каждая SamplingThereafter-я. 0 отключает сэмплирование
+	SamplingInitial    int `yaml:"samplingInitial"`
+	SamplingThereafter int `yaml:"samplingThereafter"`
+}
+
+// CORSConfig - политика для браузерных клиентов с других источников. Пустой список источников запрещает кросс-доменные запросы
+type CORSConfig struct {
+	// Точные источники (https://app.example.com), поддомены (https://*.example.com) или * для любого источника
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+	AllowedMethods []string `yaml:"allowedMethods"`
+	AllowedHeaders []string `yaml:"allowedHeaders"`
+	// Заголовки ответа, доступные скриптам
+	ExposedHeaders   []string      `yaml:"exposedHeaders"`
+	AllowCredentials bool          `yaml:"allowCredentials"`
+	MaxAge           time.Duration `yaml:"maxAge"`
+}
+
+// OpenAPIConfig - проверка запросов и ответов по спецификации API
+type OpenAPIConfig struct {
+	// Запросы, не подходящие под спецификацию, получают 400 до вызова обработчика
+	ValidateRequests bool `yaml:"validateRequests"`
+	// Ответ, не подходящий под спецификацию, заменяется на 500. Нужно для тестов, в production ответы буферизуются зря
+	ValidateResponses bool `yaml:"validateResponses"`
+}
+
+// GRPCConfig - gRPC API на отдельном порту. Пустой адрес отключает gRPC сервер
+type GRPCConfig struct {
+	Address string `yaml:"address"`
+}
+
+func (c GRPCConfig) Enabled() bool {
+	return c.Address != ""
+}
+
+// Хранилища кэша информации о пользователе
+const (
+	CacheBackendNone   = "none"
+	CacheBackendMemory = "memory"
+	CacheBackendRedis  = "redis"
+)
+
+// CacheConfig - кэш ответа /api/info. memory подходит для одной реплики: сброс кэша при переводе
+// видит только тот экземпляр, который его провёл. redis общий для всех реплик
+type CacheConfig struct {
+	Backend string `yaml:"backend"`
+	// Верхняя граница устаревания, если сброс кэша не удался
+	TTL time.Duration `yaml:"ttl"`
+	// Сколько пользователей хранит memory, при переполнении вытесняются давно не запрашивавшиеся
+	MaxEntries int `yaml:"maxEntries"`
+}
+
+func (c CacheConfig) Enabled() bool {
+	return c.Backend != CacheBackendNone
+}
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Address:            "0.0.0.0:8080",
+			ReadTimeout:        10 * time.Second,
+			ReadHeaderTimeout:  5 * time.Second,
+			WriteTimeout:       30 * time.Second,
+			IdleTimeout:        2 * time.Minute,
+			ShutdownTimeout:    20 * time.Second,
+			HealthCheckTimeout: 2 * time.Second,
+			RequestTimeout:     5 * time.Second,
+			RouteTimeouts: map[string]time.Duration{
+				"/api/leaderboard": 10 * time.Second,
+			},
+		},
+		Database: DatabaseConfig{
+			Port:             5432,
+			SSLMode:          "disable",
+			MaxOpenConns:     1000,
+			MaxIdleConns:     500,
+			ConnMaxLifetime:  30 * time.Minute,
+			ConnMaxIdleTime:  5 * time.Minute,
+			StatementTimeout: 10 * time.Second,
+			// Запас на типичное отставание асинхронной реплики
+			ReadYourWritesWindow: 5 * time.Second,
+		},
+		RateLimit: RateLimitConfig{
+			Backend:           RateLimitBackendMemory,
+			RequestsPerSecond: 100,
+			Burst:             200,
+			Routes: map[string]RateLimitBudget{
+				"/api/auth":     {RequestsPerSecond: 10, Burst: 20},
+				"/api/sendCoin": {RequestsPerSecond: 20, Burst: 40},
+				// v2 перевода монет получает тот же бюджет, что и v1
+				"/api/v2/transfers": {RequestsPerSecond: 20, Burst: 40},
+			},
+			EntryTTL: 10 * time.Minute,
+		},
+		Transfers: TransfersConfig{
+			PendingTTL:             72 * time.Hour,
+			PendingCheckInterval:   time.Minute,
+			ScheduledCheckInterval: 30 * time.Second,
+			ScheduledLease:         5 * time.Minute,
+		},
+		Tracing: TracingConfig{
+			Exporter:    TracingExporterNone,
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			ServiceName: "merch-webapp",
+			SampleRatio: 1,
+		},
+		Logging: LoggingConfig{
+			Level:              "info",
+			Encoding:           LogEncodingJSON,
+			AccessOutputs:      []string{"access.log"},
+			DBOutputs:          []string{"db.log"},
+			SamplingInitial:    100,
+			SamplingThereafter: 100,
+		},
+		CORS: CORSConfig{
+			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders:   []string{"Content-Type", "Authorization", "JWT-Token", "X-Request-ID", "If-None-Match"},
+			ExposedHeaders:   []string{"X-Request-ID", "ETag", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
+			AllowCredentials: true,
+			MaxAge:           10 * time.Minute,
+		},
+		GRPC: GRPCConfig{
+			Address: "0.0.0.0:9090",
+		},
+		Cache: CacheConfig{
+			Backend:    CacheBackendNone,
+			TTL:        30 * time.Second,
+			MaxEntries: 10000,
+		},
+	}
+}
+
+// Load собирает конфигурацию и проверяет её. Пустой path означает работу без YAML-файла
+func Load(path string) (*Config, error) {
+	cfg, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadDatabase нужен утилитам, которым достаточно подключения к БД (например, мигратору)
+func LoadDatabase(path string) (*DatabaseConfig, error) {
+	cfg, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Database.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &cfg.Database, nil
+}
+
+func read(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, fmt.Errorf("invalid environment: %w", err)
+	}
+	return &cfg, nil
+}
+
+func (c *Config) applyEnv() error {
+	env := envReader{}
+
+	env.setString("BACKEND_URL", &c.Server.Address)
+	env.setDuration("SERVER_READ_TIMEOUT", &c.Server.ReadTimeout)
+	env.setDuration("SERVER_READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout)
+	env.setDuration("SERVER_WRITE_TIMEOUT", &c.Server.WriteTimeout)
+	env.setDuration("SERVER_IDLE_TIMEOUT", &c.Server.IdleTimeout)
+	env.setDuration("SERVER_DRAIN_DELAY", &c.Server.DrainDelay)
+	env.setDuration("SERVER_SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+	env.setDuration("HEALTH_CHECK_TIMEOUT", &c.Server.HealthCheckTimeout)
+	env.setDuration("SERVER_REQUEST_TIMEOUT", &c.Server.RequestTimeout)
+
+	env.setString("DB_HOST", &c.Database.Host)
+	env.setInt("DB_PORT", &c.Database.Port)
+	env.setString("DB_USER", &c.Database.User)
+	env.setString("DB_PASS", &c.Database.Password)
+	env.setString("DB_NAME", &c.Database.Name)
+	env.setString("DB_SSLMODE", &c.Database.SSLMode)
+	env.setInt("DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns)
+	env.setInt("DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns)
+	env.setDuration("DB_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime)
+	env.setDuration("DB_CONN_MAX_IDLE_TIME", &c.Database.ConnMaxIdleTime)
+	env.setDuration("DB_STATEMENT_TIMEOUT", &c.Database.StatementTimeout)
+	env.setList("DB_REPLICAS", &c.Database.Replicas)
+	env.setDuration("DB_READ_YOUR_WRITES_WINDOW", &c.Database.ReadYourWritesWindow)
+
+	env.setString("REDIS_ADDR", &c.Redis.Address)
+	env.setString("REDIS_PASSWORD", &c.Redis.Password)
+	env.setInt("REDIS_DB", &c.Redis.DB)
+
+	env.setString("JWT_SECRET", &c.JWT.Secret)
+
+	env.setString("RATE_LIMIT_BACKEND", &c.RateLimit.Backend)
+	env.setFloat("RATE_LIMIT_RPS", &c.RateLimit.RequestsPerSecond)
+	env.setInt("RATE_LIMIT_BURST", &c.RateLimit.Burst)
+	env.setRouteBudget("RATE_LIMIT_AUTH", "/api/auth", &c.RateLimit.Routes)
+	env.setRouteBudget("RATE_LIMIT_SEND_COIN", "/api/sendCoin", &c.RateLimit.Routes)
+	env.setRouteBudget("RATE_LIMIT_SEND_COIN", "/api/v2/transfers", &c.RateLimit.Routes)
+	env.setDuration("RATE_LIMIT_ENTRY_TTL", &c.RateLimit.EntryTTL)
+	env.setList("RATE_LIMIT_TRUSTED_PROXIES", &c.RateLimit.TrustedProxies)
+
+	env.setDuration("PENDING_TRANSFER_TTL", &c.Transfers.PendingTTL)
+	env.setDuration("PENDING_TRANSFER_CHECK_INTERVAL", &c.Transfers.PendingCheckInterval)
+	env.setDuration("SCHEDULED_TRANSFER_CHECK_INTERVAL", &c.Transfers.ScheduledCheckInterval)
+	env.setDuration("SCHEDULED_TRANSFER_LEASE", &c.Transfers.ScheduledLease)
+
+	env.setString("TRACING_EXPORTER", &c.Tracing.Exporter)
+	env.setString("OTEL_EXPORTER_OTLP_ENDPOINT", &c.Tracing.Endpoint)
+	env.setBool("OTEL_EXPORTER_OTLP_INSECURE", &c.Tracing.Insecure)
+	env.setString("OTEL_SERVICE_NAME", &c.Tracing.ServiceName)
+	env.setFloat("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+
+	env.setString("LOG_LEVEL", &c.Logging.Level)
+	env.setString("LOG_ENCODING", &c.Logging.Encoding)
+	env.setList("LOG_ACCESS_OUTPUTS", &c.Logging.AccessOutputs)
+	env.setList("LOG_DB_OUTPUTS", &c.Logging.DBOutputs)
+	env.setInt("LOG_SAMPLING_INITIAL", &c.Logging.SamplingInitial)
+	env.setInt("LOG_SAMPLING_THEREAFTER", &c.Logging.SamplingThereafter)
+
+	env.setList("CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins)
+	env.setList("CORS_ALLOWED_HEADERS", &c.CORS.AllowedHeaders)
+	env.setList("CORS_EXPOSED_HEADERS", &c.CORS.ExposedHeaders)
+	env.setBool("CORS_ALLOW_CREDENTIALS", &c.CORS.AllowCredentials)
+	env.setDuration("CORS_MAX_AGE", &c.CORS.MaxAge)
+
+	env.setBool("OPENAPI_VALIDATE_REQUESTS", &c.OpenAPI.ValidateRequests)
+	env.setBool("OPENAPI_VALIDATE_RESPONSES", &c.OpenAPI.ValidateResponses)
+
+	env.setString("GRPC_ADDRESS", &c.GRPC.Address)
+
+	env.setString("CACHE_BACKEND", &c.Cache.Backend)
+	env.setDuration("CACHE_TTL", &c.Cache.TTL)
+	env.setInt("CACHE_MAX_ENTRIES", &c.Cache.MaxEntries)
+
+	return errors.Join(env.errs...)
+}
+
+// Validate возвращает все найденные ошибки конфигурации сразу
+func (c *Config) Validate() error {
+	errs := []error{c.Database.Validate()}
+
+	var v validator
+	v.check(c.Server.Address != "", "server.address is required (BACKEND_URL)")
+	v.check(c.Server.ReadTimeout > 0, "server.readTimeout must be positive")
+	v.check(c.Server.ReadHeaderTimeout > 0, "server.readHeaderTimeout must be positive")
+	v.check(c.Server.WriteTimeout > 0, "server.writeTimeout must be positive")
+	v.check(c.Server.IdleTimeout > 0, "server.idleTimeout must be positive")
+	v.check(c.Server.DrainDelay >= 0, "server.drainDelay must not be negative")
+	v.check(c.Server.ShutdownTimeout > 0, "server.shutdownTimeout must be positive")
+	v.check(c.Server.HealthCheckTimeout > 0, "server.healthCheckTimeout must be positive")
+	// Иначе сервер закроет соединение раньше, чем обработчик успеет ответить 504
+	v.check(c.Server.RequestTimeout > 0 && c.Server.RequestTimeout <= c.Server.WriteTimeout,
+		"server.requestTimeout must be positive and not exceed writeTimeout, got %s", c.Server.RequestTimeout)
+	for route, timeout := range c.Server.RouteTimeouts {
+		v.check(timeout > 0 && timeout <= c.Server.WriteTimeout,
+			"server.routeTimeouts[%s] must be positive and not exceed writeTimeout, got %s", route, timeout)
+	}
+
+	v.check(c.Redis.DB >= 0, "redis.db must not be negative, got %d", c.Redis.DB)
+
+	v.check(c.JWT.Secret != "", "jwt.secret is required (JWT_SECRET)")
+
+	switch c.RateLimit.Backend {
+	case RateLimitBackendMemory:
+	case RateLimitBackendRedis:
+		v.check(c.Redis.Enabled(), "rateLimit.backend redis requires redis.address (REDIS_ADDR)")
+	default:
+		v.check(false, "rateLimit.backend must be memory or redis, got %q", c.RateLimit.Backend)
+	}
+	v.check(c.RateLimit.RequestsPerSecond > 0, "rateLimit.requestsPerSecond must be positive, got %v", c.RateLimit.RequestsPerSecond)
+	v.check(c.RateLimit.Burst > 0, "rateLimit.burst must be positive, got %d", c.RateLimit.Burst)
+	for route, budget := range c.RateLimit.Routes {
+		v.check(budget.RequestsPerSecond > 0, "rateLimit.routes[%s].requestsPerSecond must be positive, got %v", route, budget.RequestsPerSecond)
+		v.check(budget.Burst > 0, "rateLimit.routes[%s].burst must be positive, got %d", route, budget.Burst)
+	}
+	v.check(c.RateLimit.EntryTTL > 0, "rateLimit.entryTTL must be positive")
+	for _, proxy := range c.RateLimit.TrustedProxies {
+		v.check(validTrustedProxy(proxy), "rateLimit.trustedProxies: %q is not an IP address or CIDR", proxy)
+	}
+
+	v.check(c.Transfers.PendingTTL > 0, "transfers.pendingTTL must be positive")
+	v.check(c.Transfers.PendingCheckInterval > 0, "transfers.pendingCheckInterval must be positive")
+	v.check(c.Transfers.ScheduledCheckInterval > 0, "transfers.scheduledCheckInterval must be positive")
+	v.check(c.Transfers.ScheduledLease > 0, "transfers.scheduledLease must be positive")
+
+	switch c.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterStdout:
+	case TracingExporterOTLP:
+		v.check(c.Tracing.Endpoint != "", "tracing.endpoint is required for otlp exporter (OTEL_EXPORTER_OTLP_ENDPOINT)")
+	default:
+		v.check(false, "tracing.exporter must be one of none, stdout, otlp, got %q", c.Tracing.Exporter)
+	}
+	v.check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sampleRatio must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+
+	switch c.Logging.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		v.check(false, "logging.level must be one of debug, info, warn, error, got %q", c.Logging.Level)
+	}
+	v.check(c.Logging.Encoding == LogEncodingJSON || c.Logging.Encoding == LogEncodingConsole,
+		"logging.encoding must be json or console, got %q", c.Logging.Encoding)
+	v.check(len(c.Logging.AccessOutputs) > 0, "logging.accessOutputs must not be empty")
+	v.check(len(c.Logging.DBOutputs) > 0, "logging.dbOutputs must not be empty")
+	v.check(c.Logging.SamplingInitial >= 0 && c.Logging.SamplingThereafter >= 0, "logging sampling values must not be negative")
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		v.check(validCORSOrigin(origin), "cors.allowedOrigins: %q must be *, scheme://host[:port] or scheme://*.domain", origin)
+		v.check(origin != "*" || !c.CORS.AllowCredentials, "cors.allowedOrigins: * cannot be combined with allowCredentials")
+	}
+	v.check(len(c.CORS.AllowedMethods) > 0, "cors.allowedMethods must not be empty")
+	v.check(c.CORS.MaxAge >= 0, "cors.maxAge must not be negative")
+
+	v.check(!c.GRPC.Enabled() || c.GRPC.Address != c.Server.Address,
+		"grpc.address must differ from server.address, got %q", c.GRPC.Address)
+
+	switch c.Cache.Backend {
+	case CacheBackendNone:
+	case CacheBackendMemory:
+		v.check(c.Cache.MaxEntries > 0, "cache.maxEntries must be positive for memory cache, got %d", c.Cache.MaxEntries)
+	case CacheBackendRedis:
+		v.check(c.Redis.Enabled(), "cache.backend redis requires redis.address (REDIS_ADDR)")
+	default:
+		v.check(false, "cache.backend must be none, memory or redis, got %q", c.Cache.Backend)
+	}
+	v.check(!c.Cache.Enabled() || c.Cache.TTL > 0, "cache.ttl must be positive")
+
+	if err := errors.Join(append(errs, v.err())...); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	return nil
+}
+
+func (c DatabaseConfig) Validate() error {
+	var v validator
+	v.check(c.Host != "", "database.host is required (DB_HOST)")
+	v.check(c.Port > 0 && c.Port < 65536, "database.port must be between 1 and 65535, got %d", c.Port)
+	v.check(c.User != "", "database.user is required (DB_USER)")
+	v.check(c.Name != "", "database.name is required (DB_NAME)")
+	v.check(c.MaxOpenConns > 0, "database.maxOpenConns must be positive, got %d", c.MaxOpenConns)
+	v.check(c.MaxIdleConns >= 0 && c.MaxIdleConns <= c.MaxOpenConns,
+		"database.maxIdleConns must be between 0 and maxOpenConns, got %d", c.MaxIdleConns)
+	v.check(c.StatementTimeout >= 0, "database.statementTimeout must not be negative")
+	for i, replica := range c.Replicas {
+		v.check(strings.Contains(replica, "host="), "database.replicas[%d] must be a key=value DSN with host", i)
+	}
+	v.check(c.ReadYourWritesWindow >= 0, "database.readYourWritesWindow must not be negative")
+	return v.err()
+}
+
+func validTrustedProxy(proxy string) bool {
+	if _, err := netip.ParsePrefix(proxy); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(proxy)
+	return err == nil
+}
+
+func validCORSOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	parsed, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return false
+	}
+	return parsed.Path == "" && parsed.RawQuery == "" && parsed.Fragment == "" && parsed.User == nil && !strings.Contains(parsed.Host, "*")
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) check(ok bool, format string, args ...interface{}) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf(format, args...))
+	}
+}
+
+func (v *validator) err() error {
+	return errors.Join(v.errs...)
+}
+
+// DSN собирает строку подключения к Postgres. statement_timeout передаётся как параметр сессии
+func (c DatabaseConfig) DSN() string {
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.User, c.Password, c.Name, c.SSLMode)
+	if c.StatementTimeout > 0 {
+		dsn += fmt.Sprintf(" statement_timeout=%d", c.StatementTimeout.Milliseconds())
+	}
+	return dsn
+}
+
+// ReplicaDSNs дополняет строки подключения к репликам тем же statement_timeout, что и у основной БД
+func (c DatabaseConfig) ReplicaDSNs() []string {
+	dsns := make([]string, len(c.Replicas))
+	for i, replica := range c.Replicas {
+		dsns[i] = replica
+		if c.StatementTimeout > 0 && !strings.Contains(replica, "statement_timeout=") {
+			dsns[i] += fmt.Sprintf(" statement_timeout=%d", c.StatementTimeout.Milliseconds())
+		}
+	}
+	return dsns
+}
+
+// envReader перезаписывает значения из заданных переменных окружения и копит ошибки разбора
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) setString(key string, dst *string) {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		*dst = value
+	}
+}
+
+func (e *envReader) setInt(key string, dst *int) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s must be an integer, got %q", key, value))
+		return
+	}
+	*dst = parsed
+}
+
+func (e *envReader) setFloat(key string, dst *float64) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s must be a number, got %q", key, value))
+		return
+	}
+	*dst = parsed
+}
+
+func (e *envReader) setBool(key string, dst *bool) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s must be true or false, got %q", key, value))
+		return
+	}
+	*dst = parsed
+}
+
+// setList читает значения через запятую
+func (e *envReader) setList(key string, dst *[]string) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+// setRouteBudget читает <prefix>_RPS и <prefix>_BURST в бюджет маршрута
+func (e *envReader) setRouteBudget(prefix string, route string, routes *map[string]RateLimitBudget) {
+	budget := (*routes)[route]
+	e.setFloat(prefix+"_RPS", &budget.RequestsPerSecond)
+	e.setInt(prefix+"_BURST", &budget.Burst)
+	if budget == (RateLimitBudget{}) {
+		return
+	}
+	if *routes == nil {
+		*routes = make(map[string]RateLimitBudget)
+	}
+	(*routes)[route] = budget
+}
+
+func (e *envReader) setDuration(key string, dst *time.Duration) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s must be a duration like 30s or 5m, got %q", key, value))
+		return
+	}
+	*dst = parsed
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_USER", "postgres")
+	t.Setenv("DB_NAME", "avito")
+	t.Setenv("JWT_SECRET", "secret-key")
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("Success - Defaults With Env", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("DB_PORT", "6432")
+		t.Setenv("RATE_LIMIT_RPS", "50.5")
+
+		cfg, err := Load("")
+		require.NoError(t, err)
+		assert.Equal(t, 6432, cfg.Database.Port)
+		assert.Equal(t, 50.5, cfg.RateLimit.RequestsPerSecond)
+		assert.Equal(t, 1000, cfg.Database.MaxOpenConns)
+		assert.Equal(t, "host=localhost port=6432 user=postgres password= dbname=avito sslmode=disable statement_timeout=10000", cfg.Database.DSN())
+	})
+
+	t.Run("Success - Env Overrides File", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("DB_MAX_OPEN_CONNS", "20")
+
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+server:
+  address: 127.0.0.1:9090
+  shutdownTimeout: 45s
+database:
+  maxOpenConns: 100
+  maxIdleConns: 10
+transfers:
+  pendingTTL: 24h
+`), 0o600))
+
+		cfg, err := Load(path)
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1:9090", cfg.Server.Address)
+		assert.Equal(t, 45*time.Second, cfg.Server.ShutdownTimeout)
+		assert.Equal(t, 30*time.Second, cfg.Server.WriteTimeout)
+		assert.Equal(t, 20, cfg.Database.MaxOpenConns)
+		assert.Equal(t, 10, cfg.Database.MaxIdleConns)
+		assert.Equal(t, 24*time.Hour, cfg.Transfers.PendingTTL)
+	})
+
+	t.Run("Success - Rate Limit Routes And Proxies", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("RATE_LIMIT_AUTH_BURST", "5")
+		t.Setenv("RATE_LIMIT_TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.1")
+
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+rateLimit:
+  routes:
+    /api/buy/{item}:
+      requestsPerSecond: 5
+      burst: 5
+`), 0o600))
+
+		cfg, err := Load(path)
+		require.NoError(t, err)
+		assert.Equal(t, RateLimitBudget{RequestsPerSecond: 10, Burst: 5}, cfg.RateLimit.Routes["/api/auth"])
+		assert.Equal(t, RateLimitBudget{RequestsPerSecond: 20, Burst: 40}, cfg.RateLimit.Routes["/api/sendCoin"])
+		assert.Equal(t, RateLimitBudget{RequestsPerSecond: 5, Burst: 5}, cfg.RateLimit.Routes["/api/buy/{item}"])
+		assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1"}, cfg.RateLimit.TrustedProxies)
+	})
+
+	t.Run("Fail - Invalid Rate Limit Settings", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("RATE_LIMIT_SEND_COIN_RPS", "0")
+		t.Setenv("RATE_LIMIT_TRUSTED_PROXIES", "proxy.local")
+		t.Setenv("RATE_LIMIT_BACKEND", "redis")
+
+		_, err := Load("")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "rateLimit.routes[/api/sendCoin].requestsPerSecond must be positive, got 0")
+		assert.Contains(t, err.Error(), `rateLimit.trustedProxies: "proxy.local" is not an IP address or CIDR`)
+		assert.Contains(t, err.Error(), "rateLimit.backend redis requires redis.address (REDIS_ADDR)")
+	})
+
+	t.Run("Success - gRPC Disabled By File", func(t *testing.T) {
+		setRequiredEnv(t)
+
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+grpc:
+  address: ""
+`), 0o600))
+
+		cfg, err := Load(path)
+		require.NoError(t, err)
+		assert.False(t, cfg.GRPC.Enabled())
+	})
+
+	t.Run("Fail - gRPC On HTTP Address", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("GRPC_ADDRESS", "0.0.0.0:8080")
+
+		_, err := Load("")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `grpc.address must differ from server.address, got "0.0.0.0:8080"`)
+	})
+
+	t.Run("Fail - Invalid Cache Settings", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("CACHE_BACKEND", "redis")
+		t.Setenv("CACHE_TTL", "0s")
+
+		_, err := Load("")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cache.backend redis requires redis.address (REDIS_ADDR)")
+		assert.Contains(t, err.Error(), "cache.ttl must be positive")
+	})
+
+	t.Run("Success - Read Replicas", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("DB_REPLICAS", "host=replica1 user=postgres dbname=avito, host=replica2 user=postgres dbname=avito statement_timeout=500")
+		t.Setenv("DB_READ_YOUR_WRITES_WINDOW", "2s")
+
+		cfg, err := Load("")
+		require.NoError(t, err)
+		assert.Equal(t, 2*time.Second, cfg.Database.ReadYourWritesWindow)
+		assert.Equal(t, []string{
+			"host=replica1 user=postgres dbname=avito statement_timeout=10000",
+			"host=replica2 user=postgres dbname=avito statement_timeout=500",
+		}, cfg.Database.ReplicaDSNs())
+	})
+
+	t.Run("Fail - Invalid Read Replicas", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("DB_REPLICAS", "postgres://replica1/avito")
+		t.Setenv("DB_READ_YOUR_WRITES_WINDOW", "-1s")
+
+		_, err := Load("")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "database.replicas[0] must be a key=value DSN with host")
+		assert.Contains(t, err.Error(), "database.readYourWritesWindow must not be negative")
+	})
+
+	t.Run("Fail - Reports All Missing Fields", func(t *testing.T) {
+		t.Setenv("DB_HOST", "")
+		t.Setenv("JWT_SECRET", "")
+
+		_, err := Load("")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "database.host is required (DB_HOST)")
+		assert.Contains(t, err.Error(), "jwt.secret is required (JWT_SECRET)")
+	})
+
+	t.Run("Fail - Malformed Env Value", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("PENDING_TRANSFER_TTL", "three days")
+
+		_, err := Load("")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `PENDING_TRANSFER_TTL must be a duration like 30s or 5m, got "three days"`)
+	})
+
+	t.Run("Fail - Unknown Tracing Exporter", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("TRACING_EXPORTER", "jaeger")
+		t.Setenv("TRACING_SAMPLE_RATIO", "1.5")
+
+		_, err := Load("")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `tracing.exporter must be one of none, stdout, otlp, got "jaeger"`)
+		assert.Contains(t, err.Error(), "tracing.sampleRatio must be between 0 and 1, got 1.5")
+	})
+
+	t.Run("Logging Outputs And Level", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("LOG_ACCESS_OUTPUTS", "stdout, /var/log/access.log")
+		t.Setenv("LOG_SAMPLING_INITIAL", "0")
+
+		cfg, err := Load("")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"stdout", "/var/log/access.log"}, cfg.Logging.AccessOutputs)
+		assert.Equal(t, []string{"db.log"}, cfg.Logging.DBOutputs)
+		assert.Equal(t, 0, cfg.Logging.SamplingInitial)
+
+		t.Setenv("LOG_LEVEL", "verbose")
+		_, err = Load("")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `logging.level must be one of debug, info, warn, error, got "verbose"`)
+	})
+
+	t.Run("CORS Origins", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com,https://*.example.com:8443")
+
+		cfg, err := Load("")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"https://app.example.com", "https://*.example.com:8443"}, cfg.CORS.AllowedOrigins)
+
+		t.Setenv("CORS_ALLOWED_ORIGINS", "*,app.example.com,https://app.example.com/path")
+		_, err = Load("")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cors.allowedOrigins: * cannot be combined with allowCredentials")
+		assert.Contains(t, err.Error(), `cors.allowedOrigins: "app.example.com" must be`)
+		assert.Contains(t, err.Error(), `cors.allowedOrigins: "https://app.example.com/path" must be`)
+	})
+
+	t.Run("Request Deadlines", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("SERVER_REQUEST_TIMEOUT", "2s")
+		t.Setenv("DB_STATEMENT_TIMEOUT", "0s")
+
+		cfg, err := Load("")
+		require.NoError(t, err)
+		assert.Equal(t, 2*time.Second, cfg.Server.RequestTimeout)
+		assert.Equal(t, 10*time.Second, cfg.Server.RouteTimeouts["/api/leaderboard"])
+		assert.NotContains(t, cfg.Database.DSN(), "statement_timeout")
+
+		t.Setenv("SERVER_REQUEST_TIMEOUT", "1m")
+		_, err = Load("")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "server.requestTimeout must be positive and not exceed writeTimeout, got 1m0s")
+	})
+
+	t.Run("Fail - Missing File", func(t *testing.T) {
+		setRequiredEnv(t)
+
+		_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.Error(t, err)
+	})
+}
+
+func TestLoadDatabase(t *testing.T) {
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_USER", "postgres")
+	t.Setenv("DB_NAME", "avito")
+	t.Setenv("JWT_SECRET", "")
+
+	cfg, err := LoadDatabase("")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost", cfg.Host)
+}
@@ -0,0 +1,35 @@
+package grpcapi
+
+import (
+	"avito_staj_2025/internal/service/middleware"
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+)
+
+// statusFromError переводит ошибки сценариев в коды gRPC по тем же сообщениям, что и handleError в HTTP контроллерах.
+// Тексты внутренних ошибок клиенту не отдаются
+func statusFromError(ctx context.Context, err error) error {
+	if httpStatus, message := middleware.TimeoutStatus(ctx, err); httpStatus == http.StatusGatewayTimeout {
+		return status.Error(codes.DeadlineExceeded, message)
+	} else if httpStatus != 0 {
+		return status.Error(codes.Unavailable, message)
+	}
+
+	switch err.Error() {
+	case "Input contains invalid characters", "Input exceeds character limit",
+		"not correct username", "not correct password", "amount must be greater than 0",
+		"invalid promo code", "promo code not applicable":
+		return status.Error(codes.InvalidArgument, err.Error())
+	case "item not found in merch types", "sender not found", "receiver not found", "user not found",
+		"promo code not found":
+		return status.Error(codes.NotFound, err.Error())
+	case "not enough coins", "promo code expired", "promo code usage limit reached":
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case "invalid credentials", "Invalid JWT token", "Missing JWT-Token header":
+		return status.Error(codes.Unauthenticated, err.Error())
+	default:
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
@@ -0,0 +1,118 @@
+package grpcapi
+
+import (
+	"avito_staj_2025/internal/service/logger"
+	"avito_staj_2025/internal/service/metrics"
+	"avito_staj_2025/internal/service/middleware"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// Ключи метаданных. gRPC передаёт их в нижнем регистре
+const (
+	requestIDMetadata = "x-request-id"
+	jwtTokenMetadata  = "jwt-token"
+)
+
+type userIDKey struct{}
+
+// userIDFromContext возвращает пользователя, которого authInterceptor взял из JWT
+func userIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey{}).(string)
+	return userID
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// requestIDInterceptor берёт x-request-id клиента, если он корректен, иначе создаёт новый и возвращает его в заголовке ответа
+func requestIDInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	requestID := metadataValue(ctx, requestIDMetadata)
+	if !middleware.ValidRequestID(requestID) {
+		requestID = uuid.New().String()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, requestID))
+
+	ctx = context.WithValue(ctx, middleware.RequestIDKey, requestID)
+	ctx = logger.WithFields(ctx, zap.String("request_id", requestID))
+	return handler(ctx, req)
+}
+
+func metricsInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	metrics.GRPCRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+	return resp, err
+}
+
+// recoveryInterceptor перехватывает панику обработчика, пишет стек в лог и отвечает Internal
+func recoveryInterceptor(log *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			metrics.GRPCPanicsRecovered.WithLabelValues(info.FullMethod).Inc()
+			logger.FromContext(ctx, log).Error("Handler panic recovered",
+				zap.String("method", info.FullMethod),
+				zap.Any("panic", recovered),
+				zap.ByteString("stack", debug.Stack()),
+			)
+			err = status.Error(codes.Internal, "internal server error")
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// deadlineInterceptor ограничивает вызовы без дедлайна клиента тем же сроком, что и HTTP запросы.
+// Дедлайн клиента gRPC передаёт сам, он не продлевается
+func deadlineInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+			return handler(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// authInterceptor проверяет JWT из метаданных jwt-token в том же формате, что и заголовок JWT-Token: "Bearer <token>"
+func authInterceptor(jwtToken middleware.JwtTokenService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		authHeader := metadataValue(ctx, jwtTokenMetadata)
+		if authHeader == "" {
+			return nil, statusFromError(ctx, errors.New("Missing JWT-Token header"))
+		}
+		token, ok := strings.CutPrefix(authHeader, "Bearer ")
+		if !ok {
+			return nil, statusFromError(ctx, errors.New("Invalid JWT token"))
+		}
+		claims, err := jwtToken.Validate(token)
+		if err != nil {
+			return nil, statusFromError(ctx, errors.New("Invalid JWT token"))
+		}
+
+		ctx = context.WithValue(ctx, userIDKey{}, claims.UserId)
+		ctx = logger.WithFields(ctx, zap.String("user_id", claims.UserId))
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,31 @@
+package grpcapi
+
+import (
+	merchv1 "avito_staj_2025/api/merch/v1"
+	authUsecase "avito_staj_2025/internal/auth/usecase"
+	"avito_staj_2025/internal/config"
+	merchUsecase "avito_staj_2025/internal/merch/usecase"
+	"avito_staj_2025/internal/service/middleware"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// Методы, которые вызываются без JWT
+var publicMethods = map[string]bool{
+	merchv1.AuthService_Login_FullMethodName: true,
+}
+
+// NewServer собирает gRPC сервер поверх тех же сценариев и JWT, что и HTTP API.
+// Порядок перехватчиков повторяет middleware HTTP: request_id, метрики, паника, срок запроса, авторизация
+func NewServer(auth authUsecase.AuthUsecase, merch merchUsecase.MerchUsecase, jwtToken middleware.JwtTokenService, server config.ServerConfig, log *zap.Logger) *grpc.Server {
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		requestIDInterceptor,
+		metricsInterceptor,
+		recoveryInterceptor(log),
+		deadlineInterceptor(server.RequestTimeout),
+		authInterceptor(jwtToken),
+	))
+	merchv1.RegisterAuthServiceServer(grpcServer, &authService{usecase: auth, jwtToken: jwtToken, logger: log})
+	merchv1.RegisterMerchServiceServer(grpcServer, &merchService{usecase: merch, logger: log})
+	return grpcServer
+}
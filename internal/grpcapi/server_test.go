@@ -0,0 +1,176 @@
+package grpcapi
+
+import (
+	merchv1 "avito_staj_2025/api/merch/v1"
+	"avito_staj_2025/domain"
+	authMocks "avito_staj_2025/internal/auth/mocks"
+	"avito_staj_2025/internal/config"
+	merchMocks "avito_staj_2025/internal/merch/mocks"
+	"avito_staj_2025/internal/service/middleware"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+	"time"
+)
+
+type testServer struct {
+	auth     *authMocks.MockAuthUsecase
+	merch    *merchMocks.MockMerchUsecase
+	jwtToken middleware.JwtTokenService
+	conn     *grpc.ClientConn
+}
+
+func newTestServer(t *testing.T) *testServer {
+	jwtToken, err := middleware.NewJwtToken("secret")
+	require.NoError(t, err)
+	ts := &testServer{
+		auth:     new(authMocks.MockAuthUsecase),
+		merch:    new(merchMocks.MockMerchUsecase),
+		jwtToken: jwtToken,
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(ts.auth, ts.merch, jwtToken, config.ServerConfig{RequestTimeout: time.Second}, zap.NewNop())
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	ts.conn, err = grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ts.conn.Close() })
+	return ts
+}
+
+func (ts *testServer) authorized(t *testing.T, userID string) context.Context {
+	token, err := ts.jwtToken.Create(userID, time.Now().Add(time.Hour).Unix())
+	require.NoError(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), jwtTokenMetadata, "Bearer "+token)
+}
+
+func TestLogin(t *testing.T) {
+	t.Run("Success - Token Accepted By Merch Service", func(t *testing.T) {
+		ts := newTestServer(t)
+		ts.auth.On("LoginUser", mock.Anything, "user", "password").Return("user123", nil)
+
+		resp, err := merchv1.NewAuthServiceClient(ts.conn).Login(context.Background(), &merchv1.LoginRequest{Username: "user", Password: "password"})
+		require.NoError(t, err)
+		claims, err := ts.jwtToken.Validate(resp.GetToken())
+		require.NoError(t, err)
+		assert.Equal(t, "user123", claims.UserId)
+	})
+
+	t.Run("Failure - Invalid Credentials", func(t *testing.T) {
+		ts := newTestServer(t)
+		ts.auth.On("LoginUser", mock.Anything, "user", "wrong").Return("", errors.New("invalid credentials"))
+
+		_, err := merchv1.NewAuthServiceClient(ts.conn).Login(context.Background(), &merchv1.LoginRequest{Username: "user", Password: "wrong"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		message string
+	}{
+		{"Failure - Missing Token", context.Background(), "Missing JWT-Token header"},
+		{"Failure - Missing Bearer Prefix", metadata.AppendToOutgoingContext(context.Background(), jwtTokenMetadata, "token"), "Invalid JWT token"},
+		{"Failure - Invalid Token", metadata.AppendToOutgoingContext(context.Background(), jwtTokenMetadata, "Bearer invalid"), "Invalid JWT token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t)
+
+			_, err := merchv1.NewMerchServiceClient(ts.conn).GetInfo(tt.ctx, &merchv1.GetInfoRequest{})
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+			assert.Equal(t, tt.message, status.Convert(err).Message())
+			ts.merch.AssertNotCalled(t, "GetUserMerchInformation", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestGetInfo(t *testing.T) {
+	ts := newTestServer(t)
+	info := domain.UserInformationResponse{
+		Coins:     900,
+		Inventory: []domain.InventoryResponse{{Type: "cup", Quantity: 2}},
+		CoinHistory: domain.CoinHistory{
+			Received: []domain.ReceivedResponse{{FromUser: "alice", Amount: 50}},
+			Sent:     []domain.SentResponse{{ToUser: "bob", Amount: 30}},
+		},
+	}
+	ts.merch.On("GetUserMerchInformation", mock.Anything, "user123", int64(0)).Return(info, nil)
+
+	var header metadata.MD
+	resp, err := merchv1.NewMerchServiceClient(ts.conn).GetInfo(ts.authorized(t, "user123"), &merchv1.GetInfoRequest{}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, int64(900), resp.GetCoins())
+	assert.Equal(t, "cup", resp.GetInventory()[0].GetType())
+	assert.Equal(t, int64(2), resp.GetInventory()[0].GetQuantity())
+	assert.Equal(t, "alice", resp.GetCoinHistory().GetReceived()[0].GetFromUser())
+	assert.Equal(t, "bob", resp.GetCoinHistory().GetSent()[0].GetToUser())
+	assert.NotEmpty(t, header.Get(requestIDMetadata))
+}
+
+func TestBuyItem(t *testing.T) {
+	t.Run("Success - Purchase Returned", func(t *testing.T) {
+		ts := newTestServer(t)
+		purchase := domain.Purchase{UUID: "purchase-uuid", Price: 270}
+		ts.merch.On("BuyItem", mock.Anything, "user123", "hoody", "SALE10").Return(purchase, nil)
+
+		resp, err := merchv1.NewMerchServiceClient(ts.conn).BuyItem(ts.authorized(t, "user123"), &merchv1.BuyItemRequest{Item: "hoody", PromoCode: "SALE10"})
+		require.NoError(t, err)
+		assert.Equal(t, "purchase-uuid", resp.GetPurchaseId())
+		assert.Equal(t, int64(270), resp.GetPrice())
+	})
+
+	t.Run("Failure - Not Enough Coins", func(t *testing.T) {
+		ts := newTestServer(t)
+		ts.merch.On("BuyItem", mock.Anything, "user123", "hoody", "").Return(domain.Purchase{}, errors.New("not enough coins"))
+
+		_, err := merchv1.NewMerchServiceClient(ts.conn).BuyItem(ts.authorized(t, "user123"), &merchv1.BuyItemRequest{Item: "hoody"})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
+
+func TestSendCoins(t *testing.T) {
+	t.Run("Success - Transaction Returned", func(t *testing.T) {
+		ts := newTestServer(t)
+		ts.merch.On("SendCoins", mock.Anything, "user123", "bob", 100).Return(domain.Transaction{UUID: "transaction-uuid"}, nil)
+
+		resp, err := merchv1.NewMerchServiceClient(ts.conn).SendCoins(ts.authorized(t, "user123"), &merchv1.SendCoinsRequest{ToUser: "bob", Amount: 100})
+		require.NoError(t, err)
+		assert.Equal(t, "transaction-uuid", resp.GetTransactionId())
+	})
+
+	t.Run("Failure - Internal Error Hidden", func(t *testing.T) {
+		ts := newTestServer(t)
+		ts.merch.On("SendCoins", mock.Anything, "user123", "bob", 100).Return(domain.Transaction{}, errors.New("failed to commit transaction"))
+
+		_, err := merchv1.NewMerchServiceClient(ts.conn).SendCoins(ts.authorized(t, "user123"), &merchv1.SendCoinsRequest{ToUser: "bob", Amount: 100})
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Equal(t, "internal server error", status.Convert(err).Message())
+	})
+
+	t.Run("Failure - Panic Recovered", func(t *testing.T) {
+		ts := newTestServer(t)
+		ts.merch.On("SendCoins", mock.Anything, "user123", "bob", 100).Run(func(mock.Arguments) { panic("boom") })
+
+		_, err := merchv1.NewMerchServiceClient(ts.conn).SendCoins(ts.authorized(t, "user123"), &merchv1.SendCoinsRequest{ToUser: "bob", Amount: 100})
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+}
@@ -0,0 +1,91 @@
+package grpcapi
+
+import (
+	merchv1 "avito_staj_2025/api/merch/v1"
+	authUsecase "avito_staj_2025/internal/auth/usecase"
+	merchUsecase "avito_staj_2025/internal/merch/usecase"
+	"avito_staj_2025/internal/service/logger"
+	"avito_staj_2025/internal/service/middleware"
+	"context"
+	"github.com/microcosm-cc/bluemonday"
+	"go.uber.org/zap"
+	"time"
+)
+
+type authService struct {
+	merchv1.UnimplementedAuthServiceServer
+	usecase  authUsecase.AuthUsecase
+	jwtToken middleware.JwtTokenService
+	logger   *zap.Logger
+}
+
+func (s *authService) Login(ctx context.Context, req *merchv1.LoginRequest) (*merchv1.LoginResponse, error) {
+	sanitizer := bluemonday.UGCPolicy()
+	userID, err := s.usecase.LoginUser(ctx, sanitizer.Sanitize(req.GetUsername()), sanitizer.Sanitize(req.GetPassword()))
+	if err != nil {
+		return nil, s.handleError(ctx, err)
+	}
+
+	tokenExpTime := time.Now().Add(24 * time.Hour).Unix()
+	token, err := s.jwtToken.Create(userID, tokenExpTime)
+	if err != nil {
+		return nil, s.handleError(ctx, err)
+	}
+	return &merchv1.LoginResponse{Token: token}, nil
+}
+
+func (s *authService) handleError(ctx context.Context, err error) error {
+	logger.FromContext(ctx, s.logger).Error("Handling error", zap.Error(err))
+	return statusFromError(ctx, err)
+}
+
+type merchService struct {
+	merchv1.UnimplementedMerchServiceServer
+	usecase merchUsecase.MerchUsecase
+	logger  *zap.Logger
+}
+
+func (s *merchService) GetInfo(ctx context.Context, _ *merchv1.GetInfoRequest) (*merchv1.GetInfoResponse, error) {
+	info, err := s.usecase.GetUserMerchInformation(ctx, userIDFromContext(ctx), 0)
+	if err != nil {
+		return nil, s.handleError(ctx, err)
+	}
+
+	response := &merchv1.GetInfoResponse{
+		Coins:       int64(info.Coins),
+		CoinHistory: &merchv1.CoinHistory{},
+	}
+	for _, item := range info.Inventory {
+		response.Inventory = append(response.Inventory, &merchv1.InventoryItem{Type: item.Type, Quantity: int64(item.Quantity)})
+	}
+	for _, received := range info.CoinHistory.Received {
+		response.CoinHistory.Received = append(response.CoinHistory.Received, &merchv1.ReceivedCoins{FromUser: received.FromUser, Amount: int64(received.Amount)})
+	}
+	for _, sent := range info.CoinHistory.Sent {
+		response.CoinHistory.Sent = append(response.CoinHistory.Sent, &merchv1.SentCoins{ToUser: sent.ToUser, Amount: int64(sent.Amount)})
+	}
+	return response, nil
+}
+
+func (s *merchService) BuyItem(ctx context.Context, req *merchv1.BuyItemRequest) (*merchv1.BuyItemResponse, error) {
+	sanitizer := bluemonday.UGCPolicy()
+	purchase, err := s.usecase.BuyItem(ctx, userIDFromContext(ctx), sanitizer.Sanitize(req.GetItem()), sanitizer.Sanitize(req.GetPromoCode()))
+	if err != nil {
+		return nil, s.handleError(ctx, err)
+	}
+	return &merchv1.BuyItemResponse{PurchaseId: purchase.UUID, Price: int64(purchase.Price)}, nil
+}
+
+func (s *merchService) SendCoins(ctx context.Context, req *merchv1.SendCoinsRequest) (*merchv1.SendCoinsResponse, error) {
+	sanitizer := bluemonday.UGCPolicy()
+	transaction, err := s.usecase.SendCoins(ctx, userIDFromContext(ctx), sanitizer.Sanitize(req.GetToUser()), int(req.GetAmount()))
+	if err != nil {
+		return nil, s.handleError(ctx, err)
+	}
+	return &merchv1.SendCoinsResponse{TransactionId: transaction.UUID}, nil
+}
+
+func (s *merchService) handleError(ctx context.Context, err error) error {
+	logger.FromContext(ctx, s.logger).Error("Handling error", zap.Error(err))
+	return statusFromError(ctx, err)
+}
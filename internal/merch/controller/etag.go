@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+)
+
+// infoETag строится из пользователя и версии его данных. Пользователь входит в ETag, чтобы после смены
+// аккаунта в том же браузере закэшированный ответ другого пользователя не подошёл по версии.
+// ETag слабый: порядок элементов в ответе не гарантирован, совпадают только данные
+func infoETag(userID string, version int64) string {
+	return fmt.Sprintf(`W/"%s.%d"`, userID, version)
+}
+
+// etagMatches сравнивает If-None-Match с ETag по слабому сравнению из RFC 9110: префикс W/ не учитывается
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
@@ -3,187 +3,333 @@ package controller
 import (
 	"avito_staj_2025/domain"
 	"avito_staj_2025/internal/merch/usecase"
+	"avito_staj_2025/internal/service/binding"
 	"avito_staj_2025/internal/service/logger"
 	"avito_staj_2025/internal/service/middleware"
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/gorilla/mux"
 	"github.com/microcosm-cc/bluemonday"
 	"go.uber.org/zap"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 )
 
 type MerchHandler struct {
 	usecase  usecase.MerchUsecase
 	jwtToken middleware.JwtTokenService
+	logger   *zap.Logger
 }
 
-func NewMerchHandler(usecase usecase.MerchUsecase, jwtToken middleware.JwtTokenService) *MerchHandler {
+func NewMerchHandler(usecase usecase.MerchUsecase, jwtToken middleware.JwtTokenService, logger *zap.Logger) *MerchHandler {
 	return &MerchHandler{
 		usecase:  usecase,
 		jwtToken: jwtToken,
+		logger:   logger,
 	}
 }
 
 func (h *MerchHandler) SendCoins(w http.ResponseWriter, r *http.Request) {
-	start := time.Now()
-	requestID := middleware.GetRequestID(r.Context())
-	ctx, cancel := middleware.WithTimeout(r.Context())
+	ctx := r.Context()
 	sanitizer := bluemonday.UGCPolicy()
-	defer cancel()
 
-	logger.AccessLogger.Info("Received SendCoins request",
-		zap.String("request_id", requestID),
-		zap.String("method", r.Method),
-		zap.String("url", r.URL.String()),
-	)
+	userID, err := h.authorize(r)
+	if err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+	ctx = logger.WithFields(ctx, zap.String("user_id", userID))
+	var data domain.SentRequest
+	if err = binding.JSON(w, r, &data); err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+	data.ToUser = sanitizer.Sanitize(data.ToUser)
+	if data.Pending {
+		transferID, err := h.usecase.SendCoinsPending(ctx, userID, data.ToUser, data.Amount)
+		if err != nil {
+			h.handleError(ctx, w, err)
+			return
+		}
+		h.writeJSON(ctx, w, domain.SendCoinsResponse{TransferID: transferID})
+	} else {
+		_, err = h.usecase.SendCoins(ctx, userID, data.ToUser, data.Amount)
+		if err != nil {
+			h.handleError(ctx, w, err)
+			return
+		}
 
-	authHeader := r.Header.Get("JWT-Token")
-	if authHeader == "" {
-		h.handleError(w, errors.New("Missing JWT-Token header"), requestID)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (h *MerchHandler) GetUserMerchInformation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := h.authorize(r)
+	if err != nil {
+		h.handleError(ctx, w, err)
 		return
 	}
+	ctx = logger.WithFields(ctx, zap.String("user_id", userID))
 
-	tokenString := authHeader[len("Bearer "):]
-	jwtToken, err := h.jwtToken.Validate(tokenString)
+	version, err := h.usecase.GetUserInfoVersion(ctx, userID)
 	if err != nil {
-		h.handleError(w, errors.New("Invalid JWT token"), requestID)
+		h.handleError(ctx, w, err)
 		return
 	}
-	var data domain.SentRequest
-	if err = json.NewDecoder(r.Body).Decode(&data); err != nil {
-		h.handleError(w, err, requestID)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("Vary", "JWT-Token")
+	if etag := infoETag(userID, version); etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
-	data.ToUser = sanitizer.Sanitize(data.ToUser)
-	err = h.usecase.SendCoins(ctx, jwtToken.UserId, data.ToUser, data.Amount)
+
+	response, err := h.usecase.GetUserMerchInformation(ctx, userID, version)
+	if err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+	// ETag берётся из версии, с которой собран ответ: при отставании реплики она может отличаться от прочитанной
+	w.Header().Set("ETag", infoETag(userID, response.Version))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+}
+
+func (h *MerchHandler) BuyItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := h.authorize(r)
+	if err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+	ctx = logger.WithFields(ctx, zap.String("user_id", userID))
+	itemName := mux.Vars(r)["item"]
+	promoCode := r.URL.Query().Get("promo")
+	_, err = h.usecase.BuyItem(ctx, userID, itemName, promoCode)
 	if err != nil {
-		h.handleError(w, err, requestID)
+		h.handleError(ctx, w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+}
 
-	duration := time.Since(start)
-	logger.AccessLogger.Info("Completed SendCoins request",
-		zap.String("request_id", requestID),
-		zap.Duration("duration", duration),
-		zap.Int("status", http.StatusOK),
-	)
+func (h *MerchHandler) GetCatalog(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	catalog, err := h.usecase.GetCatalog(ctx)
+	if err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+	h.writeJSON(ctx, w, catalog)
 }
 
-func (h *MerchHandler) GetUserMerchInformation(w http.ResponseWriter, r *http.Request) {
-	start := time.Now()
-	requestID := middleware.GetRequestID(r.Context())
-	ctx, cancel := middleware.WithTimeout(r.Context())
-	defer cancel()
-
-	logger.AccessLogger.Info("Received GetUserMerchInformation request",
-		zap.String("request_id", requestID),
-		zap.String("method", r.Method),
-		zap.String("url", r.URL.String()),
-	)
+func (h *MerchHandler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-	authHeader := r.Header.Get("JWT-Token")
-	if authHeader == "" {
-		h.handleError(w, errors.New("Missing JWT-Token header"), requestID)
+	if _, err := h.authorize(r); err != nil {
+		h.handleError(ctx, w, err)
 		return
 	}
 
-	tokenString := authHeader[len("Bearer "):]
-	jwtToken, err := h.jwtToken.Validate(tokenString)
+	query := r.URL.Query()
+	limit := 0
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		parsed, err := strconv.Atoi(rawLimit)
+		if err != nil {
+			h.handleError(ctx, w, errors.New("invalid limit"))
+			return
+		}
+		limit = parsed
+	}
+
+	response, err := h.usecase.GetLeaderboard(ctx, query.Get("category"), query.Get("window"), limit)
 	if err != nil {
-		h.handleError(w, errors.New("Invalid JWT token"), requestID)
+		h.handleError(ctx, w, err)
 		return
 	}
-	response, err := h.usecase.GetUserMerchInformation(ctx, jwtToken.UserId)
+	h.writeJSON(ctx, w, response)
+}
+
+func (h *MerchHandler) GetPendingTransfers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := h.authorize(r)
 	if err != nil {
-		h.handleError(w, err, requestID)
+		h.handleError(ctx, w, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		h.handleError(w, err, requestID)
+	ctx = logger.WithFields(ctx, zap.String("user_id", userID))
+	response, err := h.usecase.GetPendingTransfers(ctx, userID)
+	if err != nil {
+		h.handleError(ctx, w, err)
 		return
 	}
-	duration := time.Since(start)
-	logger.AccessLogger.Info("Completed GetUserMerchInformation request",
-		zap.String("request_id", requestID),
-		zap.Duration("duration", duration),
-		zap.Int("status", http.StatusOK))
+	h.writeJSON(ctx, w, response)
 }
 
-func (h *MerchHandler) BuyItem(w http.ResponseWriter, r *http.Request) {
-	start := time.Now()
-	requestID := middleware.GetRequestID(r.Context())
-	ctx, cancel := middleware.WithTimeout(r.Context())
-	defer cancel()
-	logger.AccessLogger.Info("Received BuyItem request",
-		zap.String("request_id", requestID),
-		zap.String("method", r.Method),
-		zap.String("url", r.URL.String()),
-	)
+func (h *MerchHandler) AcceptTransfer(w http.ResponseWriter, r *http.Request) {
+	h.resolveTransfer(w, r, h.usecase.AcceptTransfer)
+}
 
-	authHeader := r.Header.Get("JWT-Token")
-	if authHeader == "" {
-		h.handleError(w, errors.New("Missing JWT-Token header"), requestID)
+func (h *MerchHandler) DeclineTransfer(w http.ResponseWriter, r *http.Request) {
+	h.resolveTransfer(w, r, h.usecase.DeclineTransfer)
+}
+
+func (h *MerchHandler) resolveTransfer(w http.ResponseWriter, r *http.Request, resolve func(ctx context.Context, userID string, transferID string) error) {
+	ctx := r.Context()
+
+	userID, err := h.authorize(r)
+	if err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+	ctx = logger.WithFields(ctx, zap.String("user_id", userID))
+	if err := resolve(ctx, userID, mux.Vars(r)["id"]); err != nil {
+		h.handleError(ctx, w, err)
 		return
 	}
 
-	tokenString := authHeader[len("Bearer "):]
-	jwtToken, err := h.jwtToken.Validate(tokenString)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *MerchHandler) ScheduleTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	sanitizer := bluemonday.UGCPolicy()
+
+	userID, err := h.authorize(r)
 	if err != nil {
-		h.handleError(w, errors.New("Invalid JWT token"), requestID)
+		h.handleError(ctx, w, err)
 		return
 	}
-	itemName := mux.Vars(r)["item"]
-	err = h.usecase.BuyItem(ctx, jwtToken.UserId, itemName)
+	ctx = logger.WithFields(ctx, zap.String("user_id", userID))
+	var data domain.ScheduleTransferRequest
+	if err = binding.JSON(w, r, &data); err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+	data.ToUser = sanitizer.Sanitize(data.ToUser)
+	transfer, err := h.usecase.ScheduleTransfer(ctx, userID, data)
 	if err != nil {
-		h.handleError(w, err, requestID)
+		h.handleError(ctx, w, err)
 		return
 	}
+	h.writeJSON(ctx, w, transfer)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	duration := time.Since(start)
-	logger.AccessLogger.Info("Completed BuyItem request",
-		zap.String("request_id", requestID),
-		zap.Duration("duration", duration),
-		zap.Int("status", http.StatusOK))
+func (h *MerchHandler) GetScheduledTransfers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
+	userID, err := h.authorize(r)
+	if err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+	ctx = logger.WithFields(ctx, zap.String("user_id", userID))
+	transfers, err := h.usecase.GetScheduledTransfers(ctx, userID)
+	if err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+	h.writeJSON(ctx, w, transfers)
+}
+
+func (h *MerchHandler) CancelScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	h.resolveTransfer(w, r, h.usecase.CancelScheduledTransfer)
+}
+
+// authorize проверяет JWT-Token и возвращает ID пользователя
+func (h *MerchHandler) authorize(r *http.Request) (string, error) {
+	authHeader := r.Header.Get("JWT-Token")
+	if authHeader == "" {
+		return "", errors.New("Missing JWT-Token header")
+	}
+
+	tokenString, ok := strings.CutPrefix(authHeader, "Bearer ")
+	if !ok {
+		return "", errors.New("Invalid JWT token")
+	}
+	jwtToken, err := h.jwtToken.Validate(tokenString)
+	if err != nil {
+		return "", errors.New("Invalid JWT token")
+	}
+	return jwtToken.UserId, nil
+}
+
+func (h *MerchHandler) writeJSON(ctx context.Context, w http.ResponseWriter, body interface{}) {
+	h.writeJSONStatus(ctx, w, http.StatusOK, body)
 }
 
-func (h *MerchHandler) handleError(w http.ResponseWriter, err error, requestID string) {
-	logger.AccessLogger.Error("Handling error",
-		zap.String("request_id", requestID),
+func (h *MerchHandler) writeJSONStatus(ctx context.Context, w http.ResponseWriter, status int, body interface{}) {
+	log := logger.FromContext(ctx, h.logger)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error("Failed to encode response",
+			zap.Error(err),
+		)
+	}
+}
+
+func (h *MerchHandler) handleError(ctx context.Context, w http.ResponseWriter, err error) {
+	log := logger.FromContext(ctx, h.logger)
+	requestID := middleware.GetRequestID(ctx)
+	log.Error("Handling error",
 		zap.Error(err),
 	)
 
 	w.Header().Set("Content-Type", "application/json")
-	errorResponse := map[string]string{"errors": err.Error()}
-
-	switch err.Error() {
-	case "Input contains invalid characters", "Input exceeds character limit",
-		"amount must be greater than 0", "item not found in merch types", "sender not found", "receiver not found",
-		"not enough coins", "User not found":
-		w.WriteHeader(http.StatusBadRequest)
-	case "Invalid JWT token", "Missing JWT-Token header":
-		w.WriteHeader(http.StatusUnauthorized)
-	case "failed to start transaction", "failed to find sender", "failed to find receiver", "failed to update sender balance",
-		"failed to update receiver balance", "failed to create transaction record", "failed to commit transaction",
-		"failed to fetch user", "failed to fetch inventory", "failed to fetch sent transactions",
-		"failed to fetch received transactions", "failed to update user balance", "failed to add new item to inventory",
-		"failed to fetch inventory item", "failed to update inventory item":
-		w.WriteHeader(http.StatusInternalServerError)
-	default:
-		w.WriteHeader(http.StatusInternalServerError)
+	errorResponse := domain.ErrorResponse{Errors: err.Error(), RequestID: requestID}
+
+	var bindErr *binding.Error
+	if status, message := middleware.TimeoutStatus(ctx, err); status != 0 {
+		errorResponse.Errors = message
+		w.WriteHeader(status)
+	} else if errors.As(err, &bindErr) {
+		errorResponse.Fields = bindErr.Fields
+		w.WriteHeader(bindErr.Status)
+	} else {
+		switch err.Error() {
+		case "Input contains invalid characters", "Input exceeds character limit",
+			"amount must be greater than 0", "item not found in merch types", "sender not found", "receiver not found",
+			"not enough coins", "user not found", "invalid transfer id", "transfer is not pending", "transfer has expired",
+			"invalid schedule", "runAt or schedule is required", "runAt must be in the future", "cannot schedule transfer to yourself",
+			"invalid promo code", "promo code not found", "promo code expired", "promo code not applicable", "promo code usage limit reached",
+			"invalid leaderboard category", "invalid leaderboard window", "invalid limit":
+			w.WriteHeader(http.StatusBadRequest)
+		case "transfer not found", "scheduled transfer not found":
+			w.WriteHeader(http.StatusNotFound)
+		case "Invalid JWT token", "Missing JWT-Token header":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "failed to start transaction", "failed to find sender", "failed to find receiver", "failed to update sender balance",
+			"failed to update receiver balance", "failed to create transaction record", "failed to commit transaction",
+			"failed to fetch user", "failed to fetch inventory", "failed to fetch sent transactions",
+			"failed to fetch received transactions", "failed to update user balance", "failed to add new item to inventory",
+			"failed to fetch inventory item", "failed to update inventory item", "failed to create pending transfer",
+			"failed to fetch pending transfers", "failed to fetch transfer", "failed to update transfer status",
+			"failed to create scheduled transfer", "failed to fetch scheduled transfers", "failed to cancel scheduled transfer",
+			"failed to fetch promo code", "failed to update promo code", "failed to create purchase record",
+			"failed to fetch price schedule", "failed to fetch leaderboard":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
 	}
 	if jsonErr := json.NewEncoder(w).Encode(errorResponse); jsonErr != nil {
-		logger.AccessLogger.Error("Failed to encode error response",
-			zap.String("request_id", requestID),
+		log.Error("Failed to encode error response",
 			zap.Error(jsonErr),
 		)
 		http.Error(w, jsonErr.Error(), http.StatusInternalServerError)
@@ -3,35 +3,36 @@ package controller
 import (
 	"avito_staj_2025/domain"
 	"avito_staj_2025/internal/merch/mocks"
-	"avito_staj_2025/internal/service/logger"
 	"avito_staj_2025/internal/service/middleware"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt"
 	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestSendCoins(t *testing.T) {
-	logger.AccessLogger = zap.NewNop()
-
 	t.Run("Success - Coins Sent", func(t *testing.T) {
 		mockUsecase := new(mocks.MockMerchUsecase)
 		mockJWT := new(mocks.MockJwtTokenService)
-		h := NewMerchHandler(mockUsecase, mockJWT)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
 
 		requestBody := domain.SentRequest{ToUser: "receiver123", Amount: 100}
 		body, _ := json.Marshal(requestBody)
 
 		claims := &middleware.JwtCsrfClaims{UserId: "sender123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
 		mockJWT.On("Validate", "valid_token").Return(claims, nil)
-		mockUsecase.On("SendCoins", mock.Anything, "sender123", "receiver123", 100).Return(nil)
+		mockUsecase.On("SendCoins", mock.Anything, "sender123", "receiver123", 100).Return(domain.Transaction{}, nil)
 
 		r, w := createTestRequest(http.MethodPost, "/api/sendCoin", body)
 		r.Header.Set("JWT-Token", "Bearer valid_token")
@@ -52,21 +53,26 @@ func TestSendCoins(t *testing.T) {
 	t.Run("Failure - Missing JWT Token", func(t *testing.T) {
 		mockUsecase := new(mocks.MockMerchUsecase)
 		mockJWT := new(mocks.MockJwtTokenService)
-		h := NewMerchHandler(mockUsecase, mockJWT)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
 
 		r, w := createTestRequest(http.MethodPost, "/api/sendCoin", nil)
+		r = r.WithContext(context.WithValue(r.Context(), middleware.RequestIDKey, "req-1"))
 		h.SendCoins(w, r)
 
 		resp := w.Result()
 		defer resp.Body.Close()
 
 		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		var body domain.ErrorResponse
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "req-1", body.RequestID)
 	})
 
 	t.Run("Failure - Invalid JWT Token", func(t *testing.T) {
 		mockUsecase := new(mocks.MockMerchUsecase)
 		mockJWT := new(mocks.MockJwtTokenService)
-		h := NewMerchHandler(mockUsecase, mockJWT)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
 
 		mockJWT.On("Validate", "invalid_token").Return(nil, errors.New("invalid token"))
 
@@ -80,22 +86,140 @@ func TestSendCoins(t *testing.T) {
 
 		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
 	})
+
+	t.Run("Failure - Invalid Body Fields", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockJWT := new(mocks.MockJwtTokenService)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+		claims := &middleware.JwtCsrfClaims{UserId: "sender123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
+		mockJWT.On("Validate", "valid_token").Return(claims, nil)
+
+		r, w := createTestRequest(http.MethodPost, "/api/sendCoin", []byte(`{"toUser":"receiver123","amount":-5}`))
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+
+		h.SendCoins(w, r)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		var body domain.ErrorResponse
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, []domain.FieldError{{Field: "amount", Message: "must be at least 1"}}, body.Fields)
+		mockUsecase.AssertNotCalled(t, "SendCoins", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
 }
 
-func TestGetUserMerchInformation(t *testing.T) {
-	logger.AccessLogger = zap.NewNop()
+func TestSendCoinsPending(t *testing.T) {
+	mockUsecase := new(mocks.MockMerchUsecase)
+	mockJWT := new(mocks.MockJwtTokenService)
+	h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+	requestBody := domain.SentRequest{ToUser: "receiver123", Amount: 100, Pending: true}
+	body, _ := json.Marshal(requestBody)
+
+	claims := &middleware.JwtCsrfClaims{UserId: "sender123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
+	mockJWT.On("Validate", "valid_token").Return(claims, nil)
+	mockUsecase.On("SendCoinsPending", mock.Anything, "sender123", "receiver123", 100).Return("transfer-uuid", nil)
 
+	r, w := createTestRequest(http.MethodPost, "/api/sendCoin", body)
+	r.Header.Set("JWT-Token", "Bearer valid_token")
+
+	h.SendCoins(w, r)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var response domain.SendCoinsResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "transfer-uuid", response.TransferID)
+	mockUsecase.AssertNotCalled(t, "SendCoins", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetUserMerchInformation(t *testing.T) {
 	t.Run("Success - Get Merch Info", func(t *testing.T) {
 		mockUsecase := new(mocks.MockMerchUsecase)
 		mockJWT := new(mocks.MockJwtTokenService)
-		h := NewMerchHandler(mockUsecase, mockJWT)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+		claims := &middleware.JwtCsrfClaims{UserId: "user123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
+		mockJWT.On("Validate", "valid_token").Return(claims, nil)
+		mockUsecase.On("GetUserInfoVersion", mock.Anything, "user123").Return(int64(3), nil)
+		mockUsecase.On("GetUserMerchInformation", mock.Anything, "user123", int64(3)).Return(domain.UserInformationResponse{Coins: 500, Version: 3}, nil)
+
+		r, w := createTestRequest(http.MethodGet, "/api/info", nil)
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+
+		h.GetUserMerchInformation(w, r)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, `W/"user123.3"`, resp.Header.Get("ETag"))
+		assert.Equal(t, "private, no-cache", resp.Header.Get("Cache-Control"))
+	})
+
+	t.Run("Fail - User Not Found", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockJWT := new(mocks.MockJwtTokenService)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+		claims := &middleware.JwtCsrfClaims{UserId: "user123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
+		mockJWT.On("Validate", "valid_token").Return(claims, nil)
+		mockUsecase.On("GetUserInfoVersion", mock.Anything, "user123").Return(int64(0), errors.New("user not found"))
+
+		r, w := createTestRequest(http.MethodGet, "/api/info", nil)
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+
+		h.GetUserMerchInformation(w, r)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		mockUsecase.AssertNotCalled(t, "GetUserMerchInformation", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success - Not Modified", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockJWT := new(mocks.MockJwtTokenService)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+		claims := &middleware.JwtCsrfClaims{UserId: "user123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
+		mockJWT.On("Validate", "valid_token").Return(claims, nil)
+		mockUsecase.On("GetUserInfoVersion", mock.Anything, "user123").Return(int64(3), nil)
+
+		r, w := createTestRequest(http.MethodGet, "/api/info", nil)
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+		r.Header.Set("If-None-Match", `"other.1", W/"user123.3"`)
+
+		h.GetUserMerchInformation(w, r)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+		assert.Equal(t, `W/"user123.3"`, resp.Header.Get("ETag"))
+		assert.Zero(t, w.Body.Len())
+		mockUsecase.AssertNotCalled(t, "GetUserMerchInformation", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success - Stale ETag Returns Body", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockJWT := new(mocks.MockJwtTokenService)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
 
 		claims := &middleware.JwtCsrfClaims{UserId: "user123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
 		mockJWT.On("Validate", "valid_token").Return(claims, nil)
-		mockUsecase.On("GetUserMerchInformation", mock.Anything, "user123").Return(domain.UserInformationResponse{Coins: 500}, nil)
+		mockUsecase.On("GetUserInfoVersion", mock.Anything, "user123").Return(int64(4), nil)
+		mockUsecase.On("GetUserMerchInformation", mock.Anything, "user123", mock.Anything).Return(domain.UserInformationResponse{Coins: 400, Version: 4}, nil)
 
 		r, w := createTestRequest(http.MethodGet, "/api/info", nil)
 		r.Header.Set("JWT-Token", "Bearer valid_token")
+		r.Header.Set("If-None-Match", `W/"user123.3"`)
 
 		h.GetUserMerchInformation(w, r)
 
@@ -103,12 +227,13 @@ func TestGetUserMerchInformation(t *testing.T) {
 		defer resp.Body.Close()
 
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, `W/"user123.4"`, resp.Header.Get("ETag"))
 	})
 
 	t.Run("Failure - Missing JWT Token", func(t *testing.T) {
 		mockUsecase := new(mocks.MockMerchUsecase)
 		mockJWT := new(mocks.MockJwtTokenService)
-		h := NewMerchHandler(mockUsecase, mockJWT)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
 
 		r, w := createTestRequest(http.MethodGet, "/api/info", nil)
 		h.GetUserMerchInformation(w, r)
@@ -118,19 +243,81 @@ func TestGetUserMerchInformation(t *testing.T) {
 
 		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
 	})
+
+	t.Run("Failure - Header Shorter Than Bearer Prefix", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockJWT := new(mocks.MockJwtTokenService)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+		r, w := createTestRequest(http.MethodGet, "/api/info", nil)
+		r.Header.Set("JWT-Token", "abc")
+
+		assert.NotPanics(t, func() { h.GetUserMerchInformation(w, r) })
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		mockJWT.AssertNotCalled(t, "Validate", mock.Anything)
+	})
+
+	t.Run("Failure - Deadline Exceeded", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockJWT := new(mocks.MockJwtTokenService)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+		claims := &middleware.JwtCsrfClaims{UserId: "user123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
+		mockJWT.On("Validate", "valid_token").Return(claims, nil)
+		mockUsecase.On("GetUserInfoVersion", mock.Anything, "user123").Return(int64(3), nil)
+		mockUsecase.On("GetUserMerchInformation", mock.Anything, "user123", mock.Anything).
+			Return(domain.UserInformationResponse{}, errors.New("failed to fetch user"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+		defer cancel()
+		r, w := createTestRequest(http.MethodGet, "/api/info", nil)
+		r = r.WithContext(ctx)
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+
+		h.GetUserMerchInformation(w, r)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	})
+
+	t.Run("Failure - Statement Timeout", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockJWT := new(mocks.MockJwtTokenService)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+		claims := &middleware.JwtCsrfClaims{UserId: "user123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
+		mockJWT.On("Validate", "valid_token").Return(claims, nil)
+		mockUsecase.On("GetUserInfoVersion", mock.Anything, "user123").Return(int64(3), nil)
+		mockUsecase.On("GetUserMerchInformation", mock.Anything, "user123", mock.Anything).
+			Return(domain.UserInformationResponse{}, fmt.Errorf("failed to fetch user: %w", &pgconn.PgError{Code: "57014"}))
+
+		r, w := createTestRequest(http.MethodGet, "/api/info", nil)
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+
+		h.GetUserMerchInformation(w, r)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	})
 }
 
 func TestBuyItem(t *testing.T) {
-	logger.AccessLogger = zap.NewNop()
-
 	t.Run("Success - Item Purchased", func(t *testing.T) {
 		mockUsecase := new(mocks.MockMerchUsecase)
 		mockJWT := new(mocks.MockJwtTokenService)
-		h := NewMerchHandler(mockUsecase, mockJWT)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
 		item := "hoody"
 		claims := &middleware.JwtCsrfClaims{UserId: "user123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
 		mockJWT.On("Validate", "valid_token").Return(claims, nil)
-		mockUsecase.On("BuyItem", mock.Anything, "user123", item).Return(nil)
+		mockUsecase.On("BuyItem", mock.Anything, "user123", item, "").Return(domain.Purchase{}, nil)
 
 		r, w := createTestRequest(http.MethodGet, "/api/buy/"+item, nil)
 		r.Header.Set("JWT-Token", "Bearer valid_token")
@@ -144,10 +331,30 @@ func TestBuyItem(t *testing.T) {
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 	})
 
+	t.Run("Failure - Promo Code Expired", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockJWT := new(mocks.MockJwtTokenService)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+		claims := &middleware.JwtCsrfClaims{UserId: "user123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
+		mockJWT.On("Validate", "valid_token").Return(claims, nil)
+		mockUsecase.On("BuyItem", mock.Anything, "user123", "hoody", "SUMMER").Return(domain.Purchase{}, errors.New("promo code expired"))
+
+		r, w := createTestRequest(http.MethodGet, "/api/buy/hoody?promo=SUMMER", nil)
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+		r = mux.SetURLVars(r, map[string]string{"item": "hoody"})
+
+		h.BuyItem(w, r)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
 	t.Run("Failure - Invalid JWT Token", func(t *testing.T) {
 		mockUsecase := new(mocks.MockMerchUsecase)
 		mockJWT := new(mocks.MockJwtTokenService)
-		h := NewMerchHandler(mockUsecase, mockJWT)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
 
 		mockJWT.On("Validate", "invalid_token").Return(nil, errors.New("invalid token"))
 
@@ -163,8 +370,186 @@ func TestBuyItem(t *testing.T) {
 	})
 }
 
+func TestResolveTransfer(t *testing.T) {
+	t.Run("Success - Transfer Accepted", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockJWT := new(mocks.MockJwtTokenService)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+		claims := &middleware.JwtCsrfClaims{UserId: "user123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
+		mockJWT.On("Validate", "valid_token").Return(claims, nil)
+		mockUsecase.On("AcceptTransfer", mock.Anything, "user123", "transfer-uuid").Return(nil)
+
+		r, w := createTestRequest(http.MethodPost, "/api/transfers/transfer-uuid/accept", nil)
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+		r = mux.SetURLVars(r, map[string]string{"id": "transfer-uuid"})
+
+		h.AcceptTransfer(w, r)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockUsecase.AssertExpectations(t)
+	})
+
+	t.Run("Failure - Transfer Not Found", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockJWT := new(mocks.MockJwtTokenService)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+		claims := &middleware.JwtCsrfClaims{UserId: "user123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
+		mockJWT.On("Validate", "valid_token").Return(claims, nil)
+		mockUsecase.On("DeclineTransfer", mock.Anything, "user123", "transfer-uuid").Return(errors.New("transfer not found"))
+
+		r, w := createTestRequest(http.MethodPost, "/api/transfers/transfer-uuid/decline", nil)
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+		r = mux.SetURLVars(r, map[string]string{"id": "transfer-uuid"})
+
+		h.DeclineTransfer(w, r)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Failure - Missing JWT Token", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockJWT := new(mocks.MockJwtTokenService)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+		r, w := createTestRequest(http.MethodGet, "/api/transfers/pending", nil)
+		h.GetPendingTransfers(w, r)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+func TestScheduleTransfer(t *testing.T) {
+	t.Run("Success - Transfer Scheduled", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockJWT := new(mocks.MockJwtTokenService)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+		request := domain.ScheduleTransferRequest{ToUser: "receiver123", Amount: 10, Schedule: "0 9 1 * *"}
+		body, _ := json.Marshal(request)
+
+		claims := &middleware.JwtCsrfClaims{UserId: "user123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
+		mockJWT.On("Validate", "valid_token").Return(claims, nil)
+		mockUsecase.On("ScheduleTransfer", mock.Anything, "user123", request).
+			Return(domain.ScheduledTransfer{UUID: "transfer-uuid", Schedule: request.Schedule}, nil)
+
+		r, w := createTestRequest(http.MethodPost, "/api/transfers/scheduled", body)
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+
+		h.ScheduleTransfer(w, r)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var transfer domain.ScheduledTransfer
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&transfer))
+		assert.Equal(t, "transfer-uuid", transfer.UUID)
+	})
+
+	t.Run("Failure - Invalid Schedule", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockJWT := new(mocks.MockJwtTokenService)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+		request := domain.ScheduleTransferRequest{ToUser: "receiver123", Amount: 10, Schedule: "sometimes"}
+		body, _ := json.Marshal(request)
+
+		claims := &middleware.JwtCsrfClaims{UserId: "user123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
+		mockJWT.On("Validate", "valid_token").Return(claims, nil)
+		mockUsecase.On("ScheduleTransfer", mock.Anything, "user123", request).
+			Return(domain.ScheduledTransfer{}, errors.New("invalid schedule"))
+
+		r, w := createTestRequest(http.MethodPost, "/api/transfers/scheduled", body)
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+
+		h.ScheduleTransfer(w, r)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestGetCatalog(t *testing.T) {
+	mockUsecase := new(mocks.MockMerchUsecase)
+	mockJWT := new(mocks.MockJwtTokenService)
+	h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+	catalog := []domain.CatalogItem{{Name: "hoody", Price: 200, BasePrice: 300}}
+	mockUsecase.On("GetCatalog", mock.Anything).Return(catalog, nil)
+
+	r, w := createTestRequest(http.MethodGet, "/api/items", nil)
+	h.GetCatalog(w, r)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var response []domain.CatalogItem
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, catalog, response)
+}
+
+func TestGetLeaderboard(t *testing.T) {
+	t.Run("Success - Leaderboard Returned", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockJWT := new(mocks.MockJwtTokenService)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+		claims := &middleware.JwtCsrfClaims{UserId: "user123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
+		mockJWT.On("Validate", "valid_token").Return(claims, nil)
+		mockUsecase.On("GetLeaderboard", mock.Anything, "thanked", "month", 5).
+			Return(domain.LeaderboardResponse{Category: "thanked", Window: "month"}, nil)
+
+		r, w := createTestRequest(http.MethodGet, "/api/leaderboard?category=thanked&window=month&limit=5", nil)
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+
+		h.GetLeaderboard(w, r)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockUsecase.AssertExpectations(t)
+	})
+
+	t.Run("Failure - Non Numeric Limit", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockJWT := new(mocks.MockJwtTokenService)
+		h := NewMerchHandler(mockUsecase, mockJWT, zap.NewNop())
+
+		claims := &middleware.JwtCsrfClaims{UserId: "user123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
+		mockJWT.On("Validate", "valid_token").Return(claims, nil)
+
+		r, w := createTestRequest(http.MethodGet, "/api/leaderboard?limit=ten", nil)
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+
+		h.GetLeaderboard(w, r)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
 func createTestRequest(method, url string, body []byte) (*http.Request, *httptest.ResponseRecorder) {
 	r := httptest.NewRequest(method, url, bytes.NewReader(body))
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
 	w := httptest.NewRecorder()
 	return r, w
 }
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/service/binding"
+	"avito_staj_2025/internal/service/logger"
+	"github.com/microcosm-cc/bluemonday"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// CreatePurchase - POST /api/v2/purchases. В отличие от GET /api/buy/{item} возвращает созданную покупку
+func (h *MerchHandler) CreatePurchase(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := h.authorize(r)
+	if err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+	ctx = logger.WithFields(ctx, zap.String("user_id", userID))
+	var data domain.PurchaseRequest
+	if err = binding.JSON(w, r, &data); err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+	purchase, err := h.usecase.BuyItem(ctx, userID, data.Item, data.PromoCode)
+	if err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+	h.writeJSONStatus(ctx, w, http.StatusCreated, purchase)
+}
+
+// CreateTransfer - POST /api/v2/transfers. Возвращает ID перевода и для прямого, и для ожидающего перевода
+func (h *MerchHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	sanitizer := bluemonday.UGCPolicy()
+
+	userID, err := h.authorize(r)
+	if err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+	ctx = logger.WithFields(ctx, zap.String("user_id", userID))
+	var data domain.SentRequest
+	if err = binding.JSON(w, r, &data); err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+	data.ToUser = sanitizer.Sanitize(data.ToUser)
+
+	response := domain.TransferResponse{ToUser: data.ToUser, Amount: data.Amount, Status: domain.TransferStatusCompleted}
+	if data.Pending {
+		response.ID, err = h.usecase.SendCoinsPending(ctx, userID, data.ToUser, data.Amount)
+		response.Status = domain.TransferStatusPending
+	} else {
+		var transaction domain.Transaction
+		transaction, err = h.usecase.SendCoins(ctx, userID, data.ToUser, data.Amount)
+		response.ID = transaction.UUID
+	}
+	if err != nil {
+		h.handleError(ctx, w, err)
+		return
+	}
+	h.writeJSONStatus(ctx, w, http.StatusCreated, response)
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/merch/mocks"
+	"avito_staj_2025/internal/service/middleware"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"testing"
+)
+
+func newV2Handler() (*MerchHandler, *mocks.MockMerchUsecase) {
+	mockUsecase := new(mocks.MockMerchUsecase)
+	mockJWT := new(mocks.MockJwtTokenService)
+	claims := &middleware.JwtCsrfClaims{UserId: "user123", StandardClaims: jwt.StandardClaims{ExpiresAt: 86400}}
+	mockJWT.On("Validate", "valid_token").Return(claims, nil)
+	return NewMerchHandler(mockUsecase, mockJWT, zap.NewNop()), mockUsecase
+}
+
+func TestCreatePurchase(t *testing.T) {
+	t.Run("Success - Purchase Created", func(t *testing.T) {
+		h, mockUsecase := newV2Handler()
+		purchase := domain.Purchase{UUID: "purchase-uuid", UserID: "user123", ItemName: "hoody", BasePrice: 300, Price: 270, PromoCode: "SALE10"}
+		mockUsecase.On("BuyItem", mock.Anything, "user123", "hoody", "SALE10").Return(purchase, nil)
+
+		r, w := createTestRequest(http.MethodPost, "/api/v2/purchases", []byte(`{"item":"hoody","promoCode":"SALE10"}`))
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+		h.CreatePurchase(w, r)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response domain.Purchase
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, "purchase-uuid", response.UUID)
+		assert.Equal(t, 270, response.Price)
+	})
+
+	t.Run("Failure - Not Enough Coins", func(t *testing.T) {
+		h, mockUsecase := newV2Handler()
+		mockUsecase.On("BuyItem", mock.Anything, "user123", "hoody", "").Return(domain.Purchase{}, errors.New("not enough coins"))
+
+		r, w := createTestRequest(http.MethodPost, "/api/v2/purchases", []byte(`{"item":"hoody"}`))
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+		h.CreatePurchase(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var response domain.ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, "not enough coins", response.Errors)
+	})
+
+	t.Run("Failure - Missing Item", func(t *testing.T) {
+		h, mockUsecase := newV2Handler()
+
+		r, w := createTestRequest(http.MethodPost, "/api/v2/purchases", []byte(`{"promoCode":"SALE10"}`))
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+		h.CreatePurchase(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockUsecase.AssertNotCalled(t, "BuyItem", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestCreateTransfer(t *testing.T) {
+	t.Run("Success - Direct Transfer", func(t *testing.T) {
+		h, mockUsecase := newV2Handler()
+		mockUsecase.On("SendCoins", mock.Anything, "user123", "bob", 40).Return(domain.Transaction{UUID: "transaction-uuid"}, nil)
+
+		r, w := createTestRequest(http.MethodPost, "/api/v2/transfers", []byte(`{"toUser":"bob","amount":40}`))
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+		h.CreateTransfer(w, r)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response domain.TransferResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, domain.TransferResponse{ID: "transaction-uuid", ToUser: "bob", Amount: 40, Status: domain.TransferStatusCompleted}, response)
+	})
+
+	t.Run("Success - Pending Transfer", func(t *testing.T) {
+		h, mockUsecase := newV2Handler()
+		mockUsecase.On("SendCoinsPending", mock.Anything, "user123", "bob", 40).Return("pending-uuid", nil)
+
+		r, w := createTestRequest(http.MethodPost, "/api/v2/transfers", []byte(`{"toUser":"bob","amount":40,"pending":true}`))
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+		h.CreateTransfer(w, r)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response domain.TransferResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, domain.TransferResponse{ID: "pending-uuid", ToUser: "bob", Amount: 40, Status: domain.TransferStatusPending}, response)
+	})
+
+	t.Run("Failure - Receiver Not Found", func(t *testing.T) {
+		h, mockUsecase := newV2Handler()
+		mockUsecase.On("SendCoins", mock.Anything, "user123", "ghost", 40).Return(domain.Transaction{}, errors.New("receiver not found"))
+
+		r, w := createTestRequest(http.MethodPost, "/api/v2/transfers", []byte(`{"toUser":"ghost","amount":40}`))
+		r.Header.Set("JWT-Token", "Bearer valid_token")
+		h.CreateTransfer(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var response domain.ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, "receiver not found", response.Errors)
+	})
+}
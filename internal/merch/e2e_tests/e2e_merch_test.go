@@ -5,12 +5,13 @@ import (
 	auth "avito_staj_2025/internal/auth/controller"
 	authRepository "avito_staj_2025/internal/auth/repository"
 	authUsecase "avito_staj_2025/internal/auth/usecase"
+	"avito_staj_2025/internal/config"
 	merchController "avito_staj_2025/internal/merch/controller"
 	merchRepository "avito_staj_2025/internal/merch/repository"
 	merchUsecase "avito_staj_2025/internal/merch/usecase"
 	"avito_staj_2025/internal/service/dsn"
-	"avito_staj_2025/internal/service/logger"
 	"avito_staj_2025/internal/service/middleware"
+	"avito_staj_2025/internal/service/openapi"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -18,6 +19,8 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"net/http"
@@ -27,6 +30,18 @@ import (
 	"time"
 )
 
+// newContractRouter проверяет запросы и ответы по openapi.yaml, чтобы расхождение спецификации и кода ломало тесты
+func newContractRouter(t *testing.T) *mux.Router {
+	spec, err := openapi.Load()
+	assert.NoError(t, err)
+	validate, err := openapi.ValidationMiddleware(spec, config.OpenAPIConfig{ValidateRequests: true, ValidateResponses: true}, zap.NewNop())
+	assert.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.Use(validate)
+	return router
+}
+
 func createDatabaseIfNotExists() error {
 	host := os.Getenv("DB_HOST_TEST")
 	port := os.Getenv("DB_PORT_TEST")
@@ -65,7 +80,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		}
 	}()
 
-	err = tx.AutoMigrate(&domain.User{}, &domain.Inventory{}, &domain.Transaction{})
+	err = tx.AutoMigrate(&domain.User{}, &domain.Inventory{}, &domain.Transaction{}, &domain.PendingTransfer{}, &domain.ScheduledTransfer{}, &domain.PromoCode{}, &domain.Purchase{}, &domain.PriceSchedule{})
 	assert.NoError(t, err)
 
 	tx.Commit()
@@ -75,7 +90,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 }
 
 func cleanupTestDB(t *testing.T, db *gorm.DB) {
-	err := db.Migrator().DropTable(&domain.User{}, &domain.Inventory{}, &domain.Transaction{})
+	err := db.Migrator().DropTable(&domain.User{}, &domain.Inventory{}, &domain.Transaction{}, &domain.PendingTransfer{}, &domain.ScheduledTransfer{}, &domain.PromoCode{}, &domain.Purchase{}, &domain.PriceSchedule{})
 	assert.NoError(t, err)
 }
 
@@ -115,13 +130,6 @@ func TestBuyItemE2E(t *testing.T) {
 	jwtToken, err := middleware.NewJwtToken("secret-key")
 	assert.NoError(t, err)
 
-	err = logger.InitLoggers()
-	assert.NoError(t, err)
-	defer func() {
-		err := logger.SyncLoggers()
-		assert.NoError(t, err)
-	}()
-
 	userID := uuid.New().String()
 	username := fmt.Sprintf("u_%d", time.Now().UnixNano())
 	createTestUser(t, db, userID, username, 500)
@@ -134,15 +142,15 @@ func TestBuyItemE2E(t *testing.T) {
 		itemName: 100,
 	}
 
-	authRepo := authRepository.NewAuthRepository(db)
-	authUC := authUsecase.NewAuthUsecase(authRepo)
-	authHandler := auth.NewAuthHandler(authUC, jwtToken)
+	authRepo := authRepository.NewAuthRepository(db, zap.NewNop())
+	authUC := authUsecase.NewAuthUsecase(authRepo, zap.NewNop())
+	authHandler := auth.NewAuthHandler(authUC, jwtToken, zap.NewNop())
 
-	merchRepo := merchRepository.NewMerchRepository(db)
-	merchUC := merchUsecase.NewMerchUsecase(merchRepo)
-	merchHandler := merchController.NewMerchHandler(merchUC, jwtToken)
+	merchRepo := merchRepository.NewMerchRepository(db, zap.NewNop())
+	merchUC := merchUsecase.NewMerchUsecase(merchRepo, config.Default().Transfers, zap.NewNop())
+	merchHandler := merchController.NewMerchHandler(merchUC, jwtToken, zap.NewNop())
 
-	router := mux.NewRouter()
+	router := newContractRouter(t)
 	api := "/api"
 
 	router.HandleFunc(api+"/auth", authHandler.LoginUser).Methods("POST")
@@ -181,13 +189,6 @@ func TestSendCoinsE2E(t *testing.T) {
 	jwtToken, err := middleware.NewJwtToken("secret-key")
 	assert.NoError(t, err)
 
-	err = logger.InitLoggers()
-	assert.NoError(t, err)
-	defer func() {
-		err := logger.SyncLoggers()
-		assert.NoError(t, err)
-	}()
-
 	senderID := uuid.New().String()
 	receiverID := uuid.New().String() // Валидный UUID
 	senderName := fmt.Sprintf("s_%d", time.Now().UnixNano())
@@ -198,15 +199,15 @@ func TestSendCoinsE2E(t *testing.T) {
 	token, err := jwtToken.Create(senderID, time.Now().Add(24*time.Hour).Unix())
 	assert.NoError(t, err)
 
-	authRepo := authRepository.NewAuthRepository(db)
-	authUC := authUsecase.NewAuthUsecase(authRepo)
-	authHandler := auth.NewAuthHandler(authUC, jwtToken)
+	authRepo := authRepository.NewAuthRepository(db, zap.NewNop())
+	authUC := authUsecase.NewAuthUsecase(authRepo, zap.NewNop())
+	authHandler := auth.NewAuthHandler(authUC, jwtToken, zap.NewNop())
 
-	merchRepo := merchRepository.NewMerchRepository(db)
-	merchUC := merchUsecase.NewMerchUsecase(merchRepo)
-	merchHandler := merchController.NewMerchHandler(merchUC, jwtToken)
+	merchRepo := merchRepository.NewMerchRepository(db, zap.NewNop())
+	merchUC := merchUsecase.NewMerchUsecase(merchRepo, config.Default().Transfers, zap.NewNop())
+	merchHandler := merchController.NewMerchHandler(merchUC, jwtToken, zap.NewNop())
 
-	router := mux.NewRouter()
+	router := newContractRouter(t)
 	api := "/api"
 
 	router.HandleFunc(api+"/auth", authHandler.LoginUser).Methods("POST")
@@ -258,13 +259,6 @@ func TestGetUserMerchInformationE2E(t *testing.T) {
 	jwtToken, err := middleware.NewJwtToken("secret-key")
 	assert.NoError(t, err)
 
-	err = logger.InitLoggers()
-	assert.NoError(t, err)
-	defer func() {
-		err := logger.SyncLoggers()
-		assert.NoError(t, err)
-	}()
-
 	userID := uuid.New().String() // Валидный UUID
 	username := fmt.Sprintf("u_%d", time.Now().UnixNano())
 	createTestUser(t, db, userID, username, 500)
@@ -281,15 +275,15 @@ func TestGetUserMerchInformationE2E(t *testing.T) {
 	token, err := jwtToken.Create(userID, time.Now().Add(24*time.Hour).Unix())
 	assert.NoError(t, err)
 
-	authRepo := authRepository.NewAuthRepository(db)
-	authUC := authUsecase.NewAuthUsecase(authRepo)
-	authHandler := auth.NewAuthHandler(authUC, jwtToken)
+	authRepo := authRepository.NewAuthRepository(db, zap.NewNop())
+	authUC := authUsecase.NewAuthUsecase(authRepo, zap.NewNop())
+	authHandler := auth.NewAuthHandler(authUC, jwtToken, zap.NewNop())
 
-	merchRepo := merchRepository.NewMerchRepository(db)
-	merchUC := merchUsecase.NewMerchUsecase(merchRepo)
-	merchHandler := merchController.NewMerchHandler(merchUC, jwtToken)
+	merchRepo := merchRepository.NewMerchRepository(db, zap.NewNop())
+	merchUC := merchUsecase.NewMerchUsecase(merchRepo, config.Default().Transfers, zap.NewNop())
+	merchHandler := merchController.NewMerchHandler(merchUC, jwtToken, zap.NewNop())
 
-	router := mux.NewRouter()
+	router := newContractRouter(t)
 	api := "/api"
 
 	router.HandleFunc(api+"/auth", authHandler.LoginUser).Methods("POST")
@@ -342,3 +336,60 @@ func TestGetUserMerchInformationE2E(t *testing.T) {
 		assert.Equal(t, 50, received.Amount)
 	}
 }
+
+func TestGetUserMerchInformationETagE2E(t *testing.T) {
+	_ = godotenv.Load("../../../.env")
+	db := setupTestDB(t)
+	jwtToken, err := middleware.NewJwtToken("secret-key")
+	assert.NoError(t, err)
+
+	userID := uuid.New().String()
+	username := fmt.Sprintf("u_%d", time.Now().UnixNano())
+	createTestUser(t, db, userID, username, 500)
+
+	token, err := jwtToken.Create(userID, time.Now().Add(24*time.Hour).Unix())
+	assert.NoError(t, err)
+
+	itemName := "pen"
+	domain.MerchTypes = map[string]int{
+		itemName: 100,
+	}
+
+	merchRepo := merchRepository.NewMerchRepository(db, zap.NewNop())
+	merchUC := merchUsecase.NewMerchUsecase(merchRepo, config.Default().Transfers, zap.NewNop())
+	merchHandler := merchController.NewMerchHandler(merchUC, jwtToken, zap.NewNop())
+
+	router := newContractRouter(t)
+	router.HandleFunc("/api/info", merchHandler.GetUserMerchInformation).Methods("GET")
+	router.HandleFunc("/api/buy/{item}", merchHandler.BuyItem).Methods("GET")
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	get := func(path string, ifNoneMatch string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("JWT-Token", "Bearer "+token)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	first := get("/api/info", "")
+	assert.Equal(t, http.StatusOK, first.StatusCode)
+	etag := first.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, "private, no-cache", first.Header.Get("Cache-Control"))
+
+	assert.Equal(t, http.StatusNotModified, get("/api/info", etag).StatusCode)
+
+	assert.Equal(t, http.StatusOK, get("/api/buy/"+itemName, "").StatusCode)
+
+	changed := get("/api/info", etag)
+	assert.Equal(t, http.StatusOK, changed.StatusCode)
+	assert.NotEqual(t, etag, changed.Header.Get("ETag"))
+}
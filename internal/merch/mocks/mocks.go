@@ -6,47 +6,181 @@ import (
 	"context"
 	"github.com/golang-jwt/jwt"
 	"github.com/stretchr/testify/mock"
+	"time"
 )
 
 type MockMerchUsecase struct {
 	mock.Mock
 }
 
-func (m *MockMerchUsecase) SendCoins(ctx context.Context, senderID string, receiverUsername string, amount int) error {
+func (m *MockMerchUsecase) SendCoins(ctx context.Context, senderID string, receiverUsername string, amount int) (domain.Transaction, error) {
 	args := m.Called(ctx, senderID, receiverUsername, amount)
-	return args.Error(0)
+	return args.Get(0).(domain.Transaction), args.Error(1)
 }
 
-func (m *MockMerchUsecase) GetUserMerchInformation(ctx context.Context, userID string) (domain.UserInformationResponse, error) {
-	args := m.Called(ctx, userID)
+func (m *MockMerchUsecase) GetUserMerchInformation(ctx context.Context, userID string, minVersion int64) (domain.UserInformationResponse, error) {
+	args := m.Called(ctx, userID, minVersion)
 	return args.Get(0).(domain.UserInformationResponse), args.Error(1)
 }
 
-func (m *MockMerchUsecase) BuyItem(ctx context.Context, userID string, itemName string) error {
-	args := m.Called(ctx, userID, itemName)
+func (m *MockMerchUsecase) GetUserInfoVersion(ctx context.Context, userID string) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockMerchUsecase) BuyItem(ctx context.Context, userID string, itemName string, promoCode string) (domain.Purchase, error) {
+	args := m.Called(ctx, userID, itemName, promoCode)
+	return args.Get(0).(domain.Purchase), args.Error(1)
+}
+
+func (m *MockMerchUsecase) GetCatalog(ctx context.Context) ([]domain.CatalogItem, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.CatalogItem), args.Error(1)
+}
+
+func (m *MockMerchUsecase) GetLeaderboard(ctx context.Context, category string, window string, limit int) (domain.LeaderboardResponse, error) {
+	args := m.Called(ctx, category, window, limit)
+	return args.Get(0).(domain.LeaderboardResponse), args.Error(1)
+}
+
+func (m *MockMerchUsecase) SendCoinsPending(ctx context.Context, senderID string, receiverUsername string, amount int) (string, error) {
+	args := m.Called(ctx, senderID, receiverUsername, amount)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockMerchUsecase) GetPendingTransfers(ctx context.Context, userID string) (domain.PendingTransfersResponse, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(domain.PendingTransfersResponse), args.Error(1)
+}
+
+func (m *MockMerchUsecase) AcceptTransfer(ctx context.Context, userID string, transferID string) error {
+	args := m.Called(ctx, userID, transferID)
 	return args.Error(0)
 }
 
+func (m *MockMerchUsecase) DeclineTransfer(ctx context.Context, userID string, transferID string) error {
+	args := m.Called(ctx, userID, transferID)
+	return args.Error(0)
+}
+
+func (m *MockMerchUsecase) ExpirePendingTransfers(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockMerchUsecase) ScheduleTransfer(ctx context.Context, senderID string, request domain.ScheduleTransferRequest) (domain.ScheduledTransfer, error) {
+	args := m.Called(ctx, senderID, request)
+	return args.Get(0).(domain.ScheduledTransfer), args.Error(1)
+}
+
+func (m *MockMerchUsecase) GetScheduledTransfers(ctx context.Context, senderID string) ([]domain.ScheduledTransfer, error) {
+	args := m.Called(ctx, senderID)
+	return args.Get(0).([]domain.ScheduledTransfer), args.Error(1)
+}
+
+func (m *MockMerchUsecase) CancelScheduledTransfer(ctx context.Context, senderID string, transferID string) error {
+	args := m.Called(ctx, senderID, transferID)
+	return args.Error(0)
+}
+
+func (m *MockMerchUsecase) RunDueScheduledTransfers(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
 // Mock для MerchRepository
 type MockMerchRepository struct {
 	mock.Mock
 }
 
-func (m *MockMerchRepository) SendCoins(ctx context.Context, senderID string, receiverUsername string, amount int) error {
+func (m *MockMerchRepository) SendCoins(ctx context.Context, senderID string, receiverUsername string, amount int) (domain.Transaction, error) {
 	args := m.Called(ctx, senderID, receiverUsername, amount)
-	return args.Error(0)
+	return args.Get(0).(domain.Transaction), args.Error(1)
 }
 
-func (m *MockMerchRepository) GetUserMerchInformation(ctx context.Context, userID string) (domain.UserInformationResponse, error) {
-	args := m.Called(ctx, userID)
+func (m *MockMerchRepository) GetUserMerchInformation(ctx context.Context, userID string, minVersion int64) (domain.UserInformationResponse, error) {
+	args := m.Called(ctx, userID, minVersion)
 	return args.Get(0).(domain.UserInformationResponse), args.Error(1)
 }
 
-func (m *MockMerchRepository) BuyItem(ctx context.Context, userID string, itemName string, itemCost int) error {
-	args := m.Called(ctx, userID, itemName, itemCost)
+func (m *MockMerchRepository) GetUserInfoVersion(ctx context.Context, userID string) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockMerchRepository) BuyItem(ctx context.Context, userID string, itemName string, basePrice int, price int, promoCode string) (domain.Purchase, error) {
+	args := m.Called(ctx, userID, itemName, basePrice, price, promoCode)
+	return args.Get(0).(domain.Purchase), args.Error(1)
+}
+
+func (m *MockMerchRepository) CreatePendingTransfer(ctx context.Context, senderID string, receiverUsername string, amount int, expiresAt time.Time) (string, error) {
+	args := m.Called(ctx, senderID, receiverUsername, amount, expiresAt)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockMerchRepository) GetPendingTransfers(ctx context.Context, userID string) (domain.PendingTransfersResponse, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(domain.PendingTransfersResponse), args.Error(1)
+}
+
+func (m *MockMerchRepository) AcceptPendingTransfer(ctx context.Context, userID string, transferID string) (domain.Transaction, error) {
+	args := m.Called(ctx, userID, transferID)
+	return args.Get(0).(domain.Transaction), args.Error(1)
+}
+
+func (m *MockMerchRepository) DeclinePendingTransfer(ctx context.Context, userID string, transferID string) (domain.PendingTransfer, error) {
+	args := m.Called(ctx, userID, transferID)
+	return args.Get(0).(domain.PendingTransfer), args.Error(1)
+}
+
+func (m *MockMerchRepository) ExpirePendingTransfers(ctx context.Context, now time.Time) ([]domain.PendingTransfer, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]domain.PendingTransfer), args.Error(1)
+}
+
+func (m *MockMerchRepository) CreateScheduledTransfer(ctx context.Context, transfer *domain.ScheduledTransfer) error {
+	args := m.Called(ctx, transfer)
+	return args.Error(0)
+}
+
+func (m *MockMerchRepository) GetScheduledTransfers(ctx context.Context, senderID string) ([]domain.ScheduledTransfer, error) {
+	args := m.Called(ctx, senderID)
+	return args.Get(0).([]domain.ScheduledTransfer), args.Error(1)
+}
+
+func (m *MockMerchRepository) CancelScheduledTransfer(ctx context.Context, senderID string, transferID string) error {
+	args := m.Called(ctx, senderID, transferID)
 	return args.Error(0)
 }
 
+func (m *MockMerchRepository) ClaimDueScheduledTransfers(ctx context.Context, now time.Time, lease time.Duration) ([]domain.ScheduledTransfer, error) {
+	args := m.Called(ctx, now, lease)
+	return args.Get(0).([]domain.ScheduledTransfer), args.Error(1)
+}
+
+func (m *MockMerchRepository) RunScheduledTransfer(ctx context.Context, transfer *domain.ScheduledTransfer, ranAt time.Time) (domain.ScheduledRunResult, error) {
+	args := m.Called(ctx, transfer, ranAt)
+	return args.Get(0).(domain.ScheduledRunResult), args.Error(1)
+}
+
+func (m *MockMerchRepository) GetActivePriceSchedule(ctx context.Context, itemName string, now time.Time) (*domain.PriceSchedule, error) {
+	args := m.Called(ctx, itemName, now)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.PriceSchedule), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockMerchRepository) GetActivePriceSchedules(ctx context.Context, now time.Time) ([]domain.PriceSchedule, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]domain.PriceSchedule), args.Error(1)
+}
+
+func (m *MockMerchRepository) GetLeaderboard(ctx context.Context, category string, since *time.Time, limit int) ([]domain.LeaderboardEntry, error) {
+	args := m.Called(ctx, category, since, limit)
+	return args.Get(0).([]domain.LeaderboardEntry), args.Error(1)
+}
+
 // Mock для JWT
 type MockJwtTokenService struct {
 	mock.Mock
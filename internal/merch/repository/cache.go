@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/service/cache"
+	"avito_staj_2025/internal/service/logger"
+	"avito_staj_2025/internal/service/metrics"
+	"context"
+	"encoding/json"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+	"time"
+)
+
+const (
+	userInfoKeyPrefix = "user_info:"
+	// userInfoLoadTimeout ограничивает общую загрузку, которая не зависит от отмены запроса первого вызвавшего
+	userInfoLoadTimeout = 10 * time.Second
+)
+
+// cachedUserInfo - запись кэша. Version хранится отдельно, потому что в JSON ответа API она не попадает
+type cachedUserInfo struct {
+	Version int64                          `json:"version"`
+	Info    domain.UserInformationResponse `json:"info"`
+}
+
+// cachedMerchRepository кэширует GetUserMerchInformation. Остальные методы передаются в next,
+// а изменяющие баланс после успешного завершения сбрасывают кэш затронутых пользователей
+type cachedMerchRepository struct {
+	domain.MerchRepository
+	cache  cache.Cache
+	ttl    time.Duration
+	loads  singleflight.Group
+	logger *zap.Logger
+}
+
+// WithCache оборачивает репозиторий кэшем информации о пользователе. ttl ограничивает устаревание данных,
+// если сброс кэша после перевода не удался
+func WithCache(next domain.MerchRepository, store cache.Cache, ttl time.Duration, logger *zap.Logger) domain.MerchRepository {
+	return &cachedMerchRepository{
+		MerchRepository: next,
+		cache:           store,
+		ttl:             ttl,
+		logger:          logger,
+	}
+}
+
+// GetUserMerchInformation не отдаёт запись старше minVersion: загрузка, завершившаяся после сброса кэша,
+// могла записать старые данные. Версию читает вызывающий, отдельного запроса на каждое попадание нет
+func (r *cachedMerchRepository) GetUserMerchInformation(ctx context.Context, userID string, minVersion int64) (domain.UserInformationResponse, error) {
+	log := logger.FromContext(ctx, r.logger)
+	key := userInfoKeyPrefix + userID
+
+	// Недоступный кэш не должен ронять запрос: читаем из БД, как без кэша
+	data, ok, err := r.cache.Get(ctx, key)
+	if err != nil {
+		metrics.InfoCacheRequests.WithLabelValues(metrics.CacheError).Inc()
+		log.Warn("Failed to read user info cache", zap.Error(err))
+	} else if ok {
+		var entry cachedUserInfo
+		if err := json.Unmarshal(data, &entry); err != nil {
+			log.Warn("Failed to decode user info cache entry", zap.Error(err))
+		} else if entry.Version >= minVersion {
+			metrics.InfoCacheRequests.WithLabelValues(metrics.CacheHit).Inc()
+			entry.Info.Version = entry.Version
+			return entry.Info, nil
+		}
+	}
+	if err == nil {
+		metrics.InfoCacheRequests.WithLabelValues(metrics.CacheMiss).Inc()
+	}
+
+	// Одновременные промахи по одному пользователю ждут один запрос в БД. Его результат получат все ждущие,
+	// поэтому отключение первого клиента не должно отменять загрузку
+	loaded, err, _ := r.loads.Do(userID, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), userInfoLoadTimeout)
+		defer cancel()
+		info, err := r.MerchRepository.GetUserMerchInformation(ctx, userID, minVersion)
+		if err != nil {
+			return nil, err
+		}
+		if data, err := json.Marshal(cachedUserInfo{Version: info.Version, Info: info}); err == nil {
+			if err := r.cache.Set(ctx, key, data, r.ttl); err != nil {
+				log.Warn("Failed to write user info cache", zap.Error(err))
+			}
+		}
+		return info, nil
+	})
+	if err != nil {
+		return domain.UserInformationResponse{}, err
+	}
+	return loaded.(domain.UserInformationResponse), nil
+}
+
+func (r *cachedMerchRepository) SendCoins(ctx context.Context, senderID string, receiverUsername string, amount int) (domain.Transaction, error) {
+	transaction, err := r.MerchRepository.SendCoins(ctx, senderID, receiverUsername, amount)
+	if err == nil {
+		r.invalidate(ctx, transaction.SenderID, transaction.ReceiverID)
+	}
+	return transaction, err
+}
+
+func (r *cachedMerchRepository) BuyItem(ctx context.Context, userID string, itemName string, basePrice int, price int, promoCode string) (domain.Purchase, error) {
+	purchase, err := r.MerchRepository.BuyItem(ctx, userID, itemName, basePrice, price, promoCode)
+	if err == nil {
+		r.invalidate(ctx, userID)
+	}
+	return purchase, err
+}
+
+func (r *cachedMerchRepository) CreatePendingTransfer(ctx context.Context, senderID string, receiverUsername string, amount int, expiresAt time.Time) (string, error) {
+	transferID, err := r.MerchRepository.CreatePendingTransfer(ctx, senderID, receiverUsername, amount, expiresAt)
+	if err == nil {
+		r.invalidate(ctx, senderID)
+	}
+	return transferID, err
+}
+
+func (r *cachedMerchRepository) RunScheduledTransfer(ctx context.Context, transfer *domain.ScheduledTransfer, ranAt time.Time) (domain.ScheduledRunResult, error) {
+	result, err := r.MerchRepository.RunScheduledTransfer(ctx, transfer, ranAt)
+	if err == nil && result.Outcome == domain.ScheduledRunDone {
+		r.invalidate(ctx, result.Transaction.SenderID, result.Transaction.ReceiverID)
+	}
+	return result, err
+}
+
+func (r *cachedMerchRepository) AcceptPendingTransfer(ctx context.Context, userID string, transferID string) (domain.Transaction, error) {
+	transaction, err := r.MerchRepository.AcceptPendingTransfer(ctx, userID, transferID)
+	if err == nil {
+		r.invalidate(ctx, transaction.SenderID, transaction.ReceiverID)
+	}
+	return transaction, err
+}
+
+func (r *cachedMerchRepository) DeclinePendingTransfer(ctx context.Context, userID string, transferID string) (domain.PendingTransfer, error) {
+	transfer, err := r.MerchRepository.DeclinePendingTransfer(ctx, userID, transferID)
+	if err == nil {
+		r.invalidate(ctx, transfer.SenderID, transfer.ReceiverID)
+	}
+	return transfer, err
+}
+
+func (r *cachedMerchRepository) ExpirePendingTransfers(ctx context.Context, now time.Time) ([]domain.PendingTransfer, error) {
+	expired, err := r.MerchRepository.ExpirePendingTransfers(ctx, now)
+	if len(expired) > 0 {
+		userIDs := make([]string, 0, 2*len(expired))
+		for _, transfer := range expired {
+			userIDs = append(userIDs, transfer.SenderID, transfer.ReceiverID)
+		}
+		r.invalidate(ctx, userIDs...)
+	}
+	return expired, err
+}
+
+// invalidate сбрасывает кэш после фиксации транзакции. Загрузка, начатая до фиксации, забывается,
+// чтобы новые запросы не дождались из неё старых данных. Сброс не зависит от отмены запроса:
+// изменения уже в БД
+func (r *cachedMerchRepository) invalidate(ctx context.Context, userIDs ...string) {
+	keys := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		r.loads.Forget(userID)
+		keys[i] = userInfoKeyPrefix + userID
+	}
+	if err := r.cache.Delete(context.WithoutCancel(ctx), keys...); err != nil {
+		logger.FromContext(ctx, r.logger).Error("Failed to invalidate user info cache", zap.Strings("user_ids", userIDs), zap.Error(err))
+	}
+}
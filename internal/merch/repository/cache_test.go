@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/merch/mocks"
+	"avito_staj_2025/internal/service/cache"
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCachedMerchRepository(t *testing.T) {
+	ctx := context.Background()
+	info := domain.UserInformationResponse{
+		Coins:       500,
+		Inventory:   []domain.InventoryResponse{{Type: "pen", Quantity: 1}},
+		CoinHistory: domain.CoinHistory{Sent: []domain.SentResponse{}, Received: []domain.ReceivedResponse{}},
+		Version:     3,
+	}
+
+	t.Run("Success - Second Read Served From Cache", func(t *testing.T) {
+		next := new(mocks.MockMerchRepository)
+		next.On("GetUserMerchInformation", mock.Anything, "user-1", mock.Anything).Return(info, nil).Once()
+		repo := WithCache(next, cache.NewMemoryCache(10), time.Minute, zap.NewNop())
+
+		first, err := repo.GetUserMerchInformation(ctx, "user-1", info.Version)
+		require.NoError(t, err)
+		second, err := repo.GetUserMerchInformation(ctx, "user-1", info.Version)
+		require.NoError(t, err)
+
+		assert.Equal(t, info, first)
+		assert.Equal(t, info, second)
+		next.AssertNumberOfCalls(t, "GetUserMerchInformation", 1)
+	})
+
+	t.Run("Success - Send Coins Invalidates Both Parties", func(t *testing.T) {
+		next := new(mocks.MockMerchRepository)
+		next.On("GetUserMerchInformation", mock.Anything, mock.Anything, mock.Anything).Return(info, nil)
+		next.On("SendCoins", mock.Anything, "sender", "bob", 100).
+			Return(domain.Transaction{UUID: "transaction-uuid", SenderID: "sender", ReceiverID: "receiver"}, nil)
+		repo := WithCache(next, cache.NewMemoryCache(10), time.Minute, zap.NewNop())
+
+		for _, userID := range []string{"sender", "receiver", "other"} {
+			_, err := repo.GetUserMerchInformation(ctx, userID, info.Version)
+			require.NoError(t, err)
+		}
+		_, err := repo.SendCoins(ctx, "sender", "bob", 100)
+		require.NoError(t, err)
+		for _, userID := range []string{"sender", "receiver", "other"} {
+			_, err := repo.GetUserMerchInformation(ctx, userID, info.Version)
+			require.NoError(t, err)
+		}
+
+		next.AssertNumberOfCalls(t, "GetUserMerchInformation", 5)
+	})
+
+	t.Run("Success - Decline And Expire Invalidate Only Parties", func(t *testing.T) {
+		now := time.Now()
+		next := new(mocks.MockMerchRepository)
+		next.On("GetUserMerchInformation", mock.Anything, mock.Anything, mock.Anything).Return(info, nil)
+		next.On("DeclinePendingTransfer", mock.Anything, "receiver", "transfer-1").
+			Return(domain.PendingTransfer{UUID: "transfer-1", SenderID: "sender", ReceiverID: "receiver"}, nil)
+		next.On("ExpirePendingTransfers", mock.Anything, now).
+			Return([]domain.PendingTransfer{{UUID: "transfer-2", SenderID: "sender", ReceiverID: "receiver"}}, nil)
+		repo := WithCache(next, cache.NewMemoryCache(10), time.Minute, zap.NewNop())
+
+		readAll := func() {
+			for _, userID := range []string{"sender", "receiver", "other"} {
+				_, err := repo.GetUserMerchInformation(ctx, userID, info.Version)
+				require.NoError(t, err)
+			}
+		}
+		readAll()
+		_, err := repo.DeclinePendingTransfer(ctx, "receiver", "transfer-1")
+		require.NoError(t, err)
+		readAll()
+		_, err = repo.ExpirePendingTransfers(ctx, now)
+		require.NoError(t, err)
+		readAll()
+
+		next.AssertNumberOfCalls(t, "GetUserMerchInformation", 7)
+	})
+
+	t.Run("Success - Failed Purchase Keeps Cache", func(t *testing.T) {
+		next := new(mocks.MockMerchRepository)
+		next.On("GetUserMerchInformation", mock.Anything, "user-1", mock.Anything).Return(info, nil).Once()
+		next.On("BuyItem", mock.Anything, "user-1", "pen", 10, 10, "").Return(domain.Purchase{}, errors.New("not enough coins"))
+		repo := WithCache(next, cache.NewMemoryCache(10), time.Minute, zap.NewNop())
+
+		_, err := repo.GetUserMerchInformation(ctx, "user-1", info.Version)
+		require.NoError(t, err)
+		_, err = repo.BuyItem(ctx, "user-1", "pen", 10, 10, "")
+		require.Error(t, err)
+		_, err = repo.GetUserMerchInformation(ctx, "user-1", info.Version)
+		require.NoError(t, err)
+
+		next.AssertNumberOfCalls(t, "GetUserMerchInformation", 1)
+	})
+
+	t.Run("Success - Entry Older Than Known Version Treated As Miss", func(t *testing.T) {
+		updated := info
+		updated.Coins = 400
+		updated.Version = 4
+		next := new(mocks.MockMerchRepository)
+		next.On("GetUserMerchInformation", mock.Anything, "user-1", info.Version).Return(info, nil).Once()
+		next.On("GetUserMerchInformation", mock.Anything, "user-1", updated.Version).Return(updated, nil).Once()
+		repo := WithCache(next, cache.NewMemoryCache(10), time.Minute, zap.NewNop())
+
+		// Загрузка старой версии, записанная в кэш уже после перевода
+		_, err := repo.GetUserMerchInformation(ctx, "user-1", info.Version)
+		require.NoError(t, err)
+		response, err := repo.GetUserMerchInformation(ctx, "user-1", updated.Version)
+		require.NoError(t, err)
+		assert.Equal(t, updated, response)
+		response, err = repo.GetUserMerchInformation(ctx, "user-1", updated.Version)
+		require.NoError(t, err)
+		assert.Equal(t, updated, response)
+
+		next.AssertNumberOfCalls(t, "GetUserMerchInformation", 2)
+		next.AssertNotCalled(t, "GetUserInfoVersion", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success - Concurrent Misses Collapsed", func(t *testing.T) {
+		release := make(chan struct{})
+		next := new(mocks.MockMerchRepository)
+		next.On("GetUserMerchInformation", mock.Anything, "user-1", mock.Anything).
+			Run(func(mock.Arguments) { <-release }).
+			Return(info, nil)
+		repo := WithCache(next, cache.NewMemoryCache(10), time.Minute, zap.NewNop())
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				response, err := repo.GetUserMerchInformation(ctx, "user-1", info.Version)
+				assert.NoError(t, err)
+				assert.Equal(t, info, response)
+			}()
+		}
+		// Даём горутинам встать в ожидание одной загрузки
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		next.AssertNumberOfCalls(t, "GetUserMerchInformation", 1)
+	})
+
+	t.Run("Success - Cancelled First Caller Does Not Fail Waiters", func(t *testing.T) {
+		release := make(chan struct{})
+		started := make(chan struct{})
+		var loadErr error
+		next := new(mocks.MockMerchRepository)
+		next.On("GetUserMerchInformation", mock.Anything, "user-1", mock.Anything).
+			Run(func(args mock.Arguments) {
+				close(started)
+				<-release
+				loadErr = args.Get(0).(context.Context).Err()
+			}).
+			Return(info, nil).Once()
+		repo := WithCache(next, cache.NewMemoryCache(10), time.Minute, zap.NewNop())
+
+		firstCtx, cancel := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, _ = repo.GetUserMerchInformation(firstCtx, "user-1", info.Version)
+		}()
+		<-started
+		go func() {
+			defer wg.Done()
+			response, err := repo.GetUserMerchInformation(ctx, "user-1", info.Version)
+			assert.NoError(t, err)
+			assert.Equal(t, info, response)
+		}()
+		// Второй вызов должен присоединиться к загрузке до отмены первого
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		close(release)
+		wg.Wait()
+
+		assert.NoError(t, loadErr)
+		next.AssertNumberOfCalls(t, "GetUserMerchInformation", 1)
+	})
+
+	t.Run("Success - Redis Unavailable Falls Back To Database", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		defer client.Close()
+		server.Close()
+
+		next := new(mocks.MockMerchRepository)
+		next.On("GetUserMerchInformation", mock.Anything, "user-1", mock.Anything).Return(info, nil)
+		repo := WithCache(next, cache.NewRedisCache(client, "cache:"), time.Minute, zap.NewNop())
+
+		response, err := repo.GetUserMerchInformation(ctx, "user-1", info.Version)
+		require.NoError(t, err)
+		assert.Equal(t, info, response)
+	})
+}
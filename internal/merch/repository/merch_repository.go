@@ -2,32 +2,46 @@ package repository
 
 import (
 	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/service/dbrouter"
 	"avito_staj_2025/internal/service/logger"
-	"avito_staj_2025/internal/service/middleware"
 	"context"
 	"errors"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
+// merchRepository пишет в db (primary). Списки и сводки, не влияющие на изменения, читаются через router,
+// который может отправить их в реплику
 type merchRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	router *dbrouter.Router
+	logger *zap.Logger
 }
 
-func NewMerchRepository(db *gorm.DB) domain.MerchRepository {
+func NewMerchRepository(db *gorm.DB, logger *zap.Logger) domain.MerchRepository {
+	return NewRoutedMerchRepository(dbrouter.New(db, nil, 0), logger)
+}
+
+// NewRoutedMerchRepository читает через реплики роутера. Пользователи, чьи данные изменились,
+// отмечаются в роутере после фиксации транзакции
+func NewRoutedMerchRepository(router *dbrouter.Router, logger *zap.Logger) domain.MerchRepository {
 	return &merchRepository{
-		db: db,
+		db:     router.Primary(),
+		router: router,
+		logger: logger,
 	}
 }
 
-func (r *merchRepository) SendCoins(ctx context.Context, senderID string, receiverUsername string, amount int) error {
-	requestID := middleware.GetRequestID(ctx)
-	logger.DBLogger.Info("SendCoins called", zap.String("request_id", requestID), zap.String("receiverID", receiverUsername), zap.Int("amount", amount))
+func (r *merchRepository) SendCoins(ctx context.Context, senderID string, receiverUsername string, amount int) (domain.Transaction, error) {
+	log := logger.FromContext(ctx, r.logger)
+	log.Info("SendCoins called", zap.String("receiverID", receiverUsername), zap.Int("amount", amount))
 
-	tx := r.db.Begin()
+	tx := r.db.WithContext(ctx).Begin()
 	if tx.Error != nil {
-		logger.DBLogger.Error("Failed to start transaction", zap.Error(tx.Error))
-		return errors.New("failed to start transaction")
+		log.Error("Failed to start transaction", zap.Error(tx.Error))
+		return domain.Transaction{}, wrapError("failed to start transaction", tx.Error)
 	}
 
 	defer func() {
@@ -36,46 +50,59 @@ func (r *merchRepository) SendCoins(ctx context.Context, senderID string, receiv
 		}
 	}()
 
-	var sender domain.User
-	if err := tx.Where("uuid = ?", senderID).First(&sender).Error; err != nil {
+	transaction, err := r.transferCoins(tx, log, senderID, receiverUsername, amount)
+	if err != nil {
 		tx.Rollback()
+		return domain.Transaction{}, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Error("Failed to commit transaction", zap.String("sender_id", senderID), zap.String("receiver_id", transaction.ReceiverID))
+		return domain.Transaction{}, wrapError("failed to commit transaction", err)
+	}
+	r.router.MarkWritten(senderID, transaction.ReceiverID)
+
+	log.Info("Successfully sent coins", zap.String("sender_id", senderID), zap.String("receiver_id", transaction.ReceiverID), zap.Int("amount", amount))
+	return transaction, nil
+}
+
+// transferCoins переводит монеты внутри транзакции tx и записывает перевод в историю
+func (r *merchRepository) transferCoins(tx *gorm.DB, log *zap.Logger, senderID string, receiverUsername string, amount int) (domain.Transaction, error) {
+	// Строка отправителя блокируется до конца транзакции: баланс проверяется и списывается без гонки
+	// с параллельными переводами и покупками
+	var sender domain.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("uuid = ?", senderID).First(&sender).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.DBLogger.Warn("User not found", zap.String("request_id", requestID), zap.String("sender_id", senderID))
-			return errors.New("sender not found")
+			log.Warn("User not found", zap.String("sender_id", senderID))
+			return domain.Transaction{}, errors.New("sender not found")
 		}
-		logger.DBLogger.Error("Failed to get user", zap.String("request_id", requestID), zap.String("sender_id", senderID))
-		return errors.New("failed to find sender")
+		log.Error("Failed to get user", zap.String("sender_id", senderID))
+		return domain.Transaction{}, wrapError("failed to find sender", err)
 	}
 
 	var receiver domain.User
 	if err := tx.Where("username = ?", receiverUsername).First(&receiver).Error; err != nil {
-		tx.Rollback()
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.DBLogger.Warn("User not found", zap.String("request_id", requestID), zap.String("receiver_id", receiverUsername))
-			return errors.New("receiver not found")
+			log.Warn("User not found", zap.String("receiver_id", receiverUsername))
+			return domain.Transaction{}, errors.New("receiver not found")
 		}
-		logger.DBLogger.Error("Failed to get user", zap.String("request_id", requestID), zap.String("receiver_id", receiverUsername))
-		return errors.New("failed to find receiver")
+		log.Error("Failed to get user", zap.String("receiver_id", receiverUsername))
+		return domain.Transaction{}, wrapError("failed to find receiver", err)
 	}
 
 	if sender.Coins < amount {
-		tx.Rollback()
-		logger.DBLogger.Warn("Not enough coins", zap.String("request_id", requestID), zap.String("sender_id", senderID))
-		return errors.New("not enough coins")
+		log.Warn("Not enough coins", zap.String("sender_id", senderID))
+		return domain.Transaction{}, errors.New("not enough coins")
 	}
 
-	sender.Coins -= amount
-	if err := tx.Model(&domain.User{}).Where("uuid = ?", senderID).Update("coins", sender.Coins).Error; err != nil {
-		tx.Rollback()
-		logger.DBLogger.Error("Failed to update sender coins", zap.String("request_id", requestID), zap.String("sender_id", senderID))
-		return errors.New("failed to update sender balance")
+	if err := tx.Model(&domain.User{}).Where("uuid = ?", senderID).Updates(balanceUpdate(sender.Coins - amount)).Error; err != nil {
+		log.Error("Failed to update sender coins", zap.String("sender_id", senderID))
+		return domain.Transaction{}, wrapError("failed to update sender balance", err)
 	}
 
-	receiver.Coins += amount
-	if err := tx.Model(&domain.User{}).Where("uuid = ?", receiver.UUID).Update("coins", receiver.Coins).Error; err != nil {
-		tx.Rollback()
-		logger.DBLogger.Error("Failed to update receiver coins", zap.String("request_id", requestID), zap.String("receiver_id", receiver.UUID))
-		return errors.New("failed to update receiver balance")
+	if err := tx.Model(&domain.User{}).Where("uuid = ?", receiver.UUID).Updates(balanceUpdate(gorm.Expr("coins + ?", amount))).Error; err != nil {
+		log.Error("Failed to update receiver coins", zap.String("receiver_id", receiver.UUID))
+		return domain.Transaction{}, wrapError("failed to update receiver balance", err)
 	}
 
 	transaction := domain.Transaction{
@@ -85,41 +112,34 @@ func (r *merchRepository) SendCoins(ctx context.Context, senderID string, receiv
 	}
 
 	if err := tx.Create(&transaction).Error; err != nil {
-		tx.Rollback()
-		logger.DBLogger.Error("Failed to create transaction", zap.String("request_id", requestID), zap.String("sender_id", senderID), zap.String("receiver_id", receiver.UUID))
-		return errors.New("failed to create transaction record")
-	}
-
-	if err := tx.Commit().Error; err != nil {
-		logger.DBLogger.Error("Failed to commit transaction", zap.String("request_id", requestID), zap.String("sender_id", senderID), zap.String("receiver_id", receiver.UUID))
-		return errors.New("failed to commit transaction")
+		log.Error("Failed to create transaction", zap.String("sender_id", senderID), zap.String("receiver_id", receiver.UUID))
+		return domain.Transaction{}, wrapError("failed to create transaction record", err)
 	}
-
-	logger.DBLogger.Info("Successfully sent coins", zap.String("request_id", requestID), zap.String("sender_id", senderID), zap.String("receiver_id", receiver.UUID), zap.Int("amount", amount))
-	return nil
+	return transaction, nil
 }
 
-func (r *merchRepository) GetUserMerchInformation(ctx context.Context, userID string) (domain.UserInformationResponse, error) {
-	requestID := middleware.GetRequestID(ctx)
-	logger.DBLogger.Info("GetUserMerchInformation called", zap.String("request_id", requestID), zap.String("user_id", userID))
+// GetUserMerchInformation всегда читает БД, minVersion используется только кэшем
+func (r *merchRepository) GetUserMerchInformation(ctx context.Context, userID string, minVersion int64) (domain.UserInformationResponse, error) {
+	log := logger.FromContext(ctx, r.logger)
+	log.Info("GetUserMerchInformation called")
 
 	var response domain.UserInformationResponse
 
-	if err := r.db.Transaction(func(tx *gorm.DB) error {
+	if err := r.router.Read(userID).WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		var user domain.User
 		if err := tx.Where("uuid = ?", userID).First(&user).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				logger.DBLogger.Warn("User not found", zap.String("request_id", requestID), zap.String("user_id", userID))
+				log.Warn("User not found")
 				return errors.New("user not found")
 			}
-			logger.DBLogger.Error("Failed to get user", zap.String("request_id", requestID), zap.Error(err))
-			return errors.New("failed to fetch user")
+			log.Error("Failed to get user", zap.Error(err))
+			return wrapError("failed to fetch user", err)
 		}
 
 		var inventory []domain.Inventory
 		if err := tx.Where("owner_id = ?", userID).Find(&inventory).Error; err != nil {
-			logger.DBLogger.Error("Failed to get inventory", zap.String("request_id", requestID), zap.Error(err))
-			return errors.New("failed to fetch inventory")
+			log.Error("Failed to get inventory", zap.Error(err))
+			return wrapError("failed to fetch inventory", err)
 		}
 
 		var transactions []domain.TransactionWithUsers
@@ -130,11 +150,12 @@ func (r *merchRepository) GetUserMerchInformation(ctx context.Context, userID st
 			Joins("JOIN users AS receiver ON transactions.receiver_id = receiver.uuid").
 			Where("transactions.sender_id = ? OR transactions.receiver_id = ?", userID, userID).
 			Scan(&transactions).Error; err != nil {
-			return errors.New("failed to fetch transactions")
+			return wrapError("failed to fetch transactions", err)
 		}
 
 		response = domain.UserInformationResponse{
 			Coins:     user.Coins,
+			Version:   user.InfoVersion,
 			Inventory: make([]domain.InventoryResponse, len(inventory)),
 			CoinHistory: domain.CoinHistory{
 				Sent:     make([]domain.SentResponse, 0),
@@ -168,33 +189,58 @@ func (r *merchRepository) GetUserMerchInformation(ctx context.Context, userID st
 		return domain.UserInformationResponse{}, err
 	}
 
-	logger.DBLogger.Info("Successfully get information", zap.String("request_id", requestID), zap.String("user_id", userID))
+	log.Info("Successfully get information")
 	return response, nil
 }
 
-func (r *merchRepository) BuyItem(ctx context.Context, userID string, itemName string, itemCost int) error {
-	requestID := middleware.GetRequestID(ctx)
-	logger.DBLogger.Info("BuyItem called", zap.String("request_id", requestID), zap.String("itemName", itemName), zap.String("user_id", userID))
+// GetUserInfoVersion читает только версию данных пользователя, без инвентаря и истории переводов
+func (r *merchRepository) GetUserInfoVersion(ctx context.Context, userID string) (int64, error) {
+	log := logger.FromContext(ctx, r.logger)
+
+	var user domain.User
+	if err := r.router.Read(userID).WithContext(ctx).Select("info_version").Where("uuid = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warn("User not found")
+			return 0, errors.New("user not found")
+		}
+		log.Error("Failed to get user info version", zap.Error(err))
+		return 0, wrapError("failed to fetch user", err)
+	}
+	return user.InfoVersion, nil
+}
+
+func (r *merchRepository) BuyItem(ctx context.Context, userID string, itemName string, basePrice int, price int, promoCode string) (domain.Purchase, error) {
+	log := logger.FromContext(ctx, r.logger)
+	log.Info("BuyItem called", zap.String("itemName", itemName), zap.String("promo_code", promoCode))
 
-	if err := r.db.Transaction(func(tx *gorm.DB) error {
+	var purchase domain.Purchase
+	if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		var user domain.User
-		if err := tx.Where("uuid = ?", userID).First(&user).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("uuid = ?", userID).First(&user).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				logger.DBLogger.Warn("User not found", zap.String("request_id", requestID), zap.String("user_id", userID))
+				log.Warn("User not found")
 				return errors.New("user not found")
 			}
-			logger.DBLogger.Error("Failed to get user", zap.String("request_id", requestID), zap.Error(err))
-			return errors.New("failed to fetch user")
+			log.Error("Failed to get user", zap.Error(err))
+			return wrapError("failed to fetch user", err)
+		}
+
+		if promoCode != "" {
+			discounted, err := r.redeemPromoCode(tx, log, promoCode, itemName, price)
+			if err != nil {
+				return err
+			}
+			price = discounted
 		}
 
-		if user.Coins < itemCost {
-			logger.DBLogger.Warn("Not enough coins", zap.String("request_id", requestID), zap.String("user_id", userID))
+		if user.Coins < price {
+			log.Warn("Not enough coins")
 			return errors.New("not enough coins")
 		}
 
-		if err := tx.Model(&domain.User{}).Where("uuid = ?", userID).Update("coins", user.Coins-itemCost).Error; err != nil {
-			logger.DBLogger.Error("Failed to update user coins", zap.String("request_id", requestID), zap.Error(err))
-			return errors.New("failed to update user balance")
+		if err := tx.Model(&domain.User{}).Where("uuid = ?", userID).Updates(balanceUpdate(user.Coins - price)).Error; err != nil {
+			log.Error("Failed to update user coins", zap.Error(err))
+			return wrapError("failed to update user balance", err)
 		}
 
 		if err := tx.Exec(`
@@ -203,15 +249,541 @@ func (r *merchRepository) BuyItem(ctx context.Context, userID string, itemName s
 			ON CONFLICT (owner_id, item_name)
 			DO UPDATE SET item_amount = inventories.item_amount + 1
 		`, userID, itemName).Error; err != nil {
-			logger.DBLogger.Error("Failed to update inventory", zap.String("request_id", requestID), zap.Error(err))
-			return errors.New("failed to update inventory")
+			log.Error("Failed to update inventory", zap.Error(err))
+			return wrapError("failed to update inventory", err)
+		}
+
+		purchase = domain.Purchase{
+			UserID:    userID,
+			ItemName:  itemName,
+			BasePrice: basePrice,
+			Price:     price,
+			PromoCode: promoCode,
+		}
+		if err := tx.Create(&purchase).Error; err != nil {
+			log.Error("Failed to create purchase", zap.Error(err))
+			return wrapError("failed to create purchase record", err)
+		}
+
+		return nil
+	}); err != nil {
+		return domain.Purchase{}, err
+	}
+	r.router.MarkWritten(userID)
+
+	log.Info("Item successfully purchased", zap.String("item_name", itemName), zap.Int("price", price))
+	return purchase, nil
+}
+
+// redeemPromoCode проверяет промокод, учитывает его использование и возвращает цену со скидкой
+func (r *merchRepository) redeemPromoCode(tx *gorm.DB, log *zap.Logger, code string, itemName string, price int) (int, error) {
+	var promo domain.PromoCode
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&promo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warn("Promo code not found", zap.String("promo_code", code))
+			return 0, errors.New("promo code not found")
+		}
+		log.Error("Failed to get promo code", zap.Error(err))
+		return 0, wrapError("failed to fetch promo code", err)
+	}
+
+	if promo.ExpiresAt != nil && !promo.ExpiresAt.After(time.Now()) {
+		log.Warn("Promo code expired", zap.String("promo_code", code))
+		return 0, errors.New("promo code expired")
+	}
+	if promo.ItemName != "" && promo.ItemName != itemName {
+		log.Warn("Promo code not applicable", zap.String("promo_code", code), zap.String("item_name", itemName))
+		return 0, errors.New("promo code not applicable")
+	}
+	if promo.MaxUses > 0 && promo.UsedCount >= promo.MaxUses {
+		log.Warn("Promo code usage limit reached", zap.String("promo_code", code))
+		return 0, errors.New("promo code usage limit reached")
+	}
+
+	if err := tx.Model(&domain.PromoCode{}).Where("code = ?", code).Update("used_count", gorm.Expr("used_count + 1")).Error; err != nil {
+		log.Error("Failed to update promo code", zap.Error(err))
+		return 0, wrapError("failed to update promo code", err)
+	}
+
+	return promo.DiscountedPrice(price), nil
+}
+
+func (r *merchRepository) CreatePendingTransfer(ctx context.Context, senderID string, receiverUsername string, amount int, expiresAt time.Time) (string, error) {
+	log := logger.FromContext(ctx, r.logger)
+	log.Info("CreatePendingTransfer called", zap.String("receiverID", receiverUsername), zap.Int("amount", amount))
+
+	var transfer domain.PendingTransfer
+	if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var sender domain.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("uuid = ?", senderID).First(&sender).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Warn("User not found", zap.String("sender_id", senderID))
+				return errors.New("sender not found")
+			}
+			log.Error("Failed to get user", zap.String("sender_id", senderID))
+			return wrapError("failed to find sender", err)
+		}
+
+		var receiver domain.User
+		if err := tx.Where("username = ?", receiverUsername).First(&receiver).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Warn("User not found", zap.String("receiver_id", receiverUsername))
+				return errors.New("receiver not found")
+			}
+			log.Error("Failed to get user", zap.String("receiver_id", receiverUsername))
+			return wrapError("failed to find receiver", err)
+		}
+
+		if sender.Coins < amount {
+			log.Warn("Not enough coins", zap.String("sender_id", senderID))
+			return errors.New("not enough coins")
+		}
+
+		// Монеты списываются сразу и удерживаются до решения получателя
+		if err := tx.Model(&domain.User{}).Where("uuid = ?", senderID).Updates(balanceUpdate(sender.Coins - amount)).Error; err != nil {
+			log.Error("Failed to update sender coins", zap.String("sender_id", senderID))
+			return wrapError("failed to update sender balance", err)
+		}
+
+		transfer = domain.PendingTransfer{
+			SenderID:   senderID,
+			ReceiverID: receiver.UUID,
+			Amount:     amount,
+			Status:     domain.TransferStatusPending,
+			ExpiresAt:  expiresAt,
+		}
+		if err := tx.Create(&transfer).Error; err != nil {
+			log.Error("Failed to create pending transfer", zap.String("sender_id", senderID), zap.String("receiver_id", receiver.UUID))
+			return wrapError("failed to create pending transfer", err)
 		}
 
 		return nil
 	}); err != nil {
-		return err
+		return "", err
+	}
+	r.router.MarkWritten(senderID, transfer.ReceiverID)
+
+	log.Info("Pending transfer created", zap.String("transfer_id", transfer.UUID), zap.String("sender_id", senderID), zap.Int("amount", amount))
+	return transfer.UUID, nil
+}
+
+func (r *merchRepository) GetPendingTransfers(ctx context.Context, userID string) (domain.PendingTransfersResponse, error) {
+	log := logger.FromContext(ctx, r.logger)
+	log.Info("GetPendingTransfers called")
+
+	var transfers []domain.PendingTransferWithUsers
+	if err := r.router.Read(userID).WithContext(ctx).
+		Table("pending_transfers").
+		Select("pending_transfers.uuid, pending_transfers.sender_id, pending_transfers.amount, pending_transfers.expires_at, sender.username AS sender_name, receiver.username AS receiver_name").
+		Joins("JOIN users AS sender ON pending_transfers.sender_id = sender.uuid").
+		Joins("JOIN users AS receiver ON pending_transfers.receiver_id = receiver.uuid").
+		Where("pending_transfers.status = ? AND (pending_transfers.sender_id = ? OR pending_transfers.receiver_id = ?)", domain.TransferStatusPending, userID, userID).
+		Order("pending_transfers.expires_at").
+		Scan(&transfers).Error; err != nil {
+		log.Error("Failed to get pending transfers", zap.Error(err))
+		return domain.PendingTransfersResponse{}, wrapError("failed to fetch pending transfers", err)
+	}
+
+	response := domain.PendingTransfersResponse{
+		Incoming: make([]domain.PendingTransferResponse, 0),
+		Outgoing: make([]domain.PendingTransferResponse, 0),
+	}
+	for _, t := range transfers {
+		item := domain.PendingTransferResponse{
+			ID:        t.UUID,
+			FromUser:  t.SenderName,
+			ToUser:    t.ReceiverName,
+			Amount:    t.Amount,
+			ExpiresAt: t.ExpiresAt,
+		}
+		if t.SenderID == userID {
+			response.Outgoing = append(response.Outgoing, item)
+		} else {
+			response.Incoming = append(response.Incoming, item)
+		}
+	}
+
+	return response, nil
+}
+
+func (r *merchRepository) AcceptPendingTransfer(ctx context.Context, userID string, transferID string) (domain.Transaction, error) {
+	log := logger.FromContext(ctx, r.logger)
+	log.Info("AcceptPendingTransfer called", zap.String("transfer_id", transferID))
+
+	var transaction domain.Transaction
+	if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transfer, err := r.lockIncomingTransfer(tx, log, userID, transferID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&domain.User{}).Where("uuid = ?", transfer.ReceiverID).Updates(balanceUpdate(gorm.Expr("coins + ?", transfer.Amount))).Error; err != nil {
+			log.Error("Failed to update receiver coins", zap.String("receiver_id", transfer.ReceiverID))
+			return wrapError("failed to update receiver balance", err)
+		}
+		// Баланс отправителя списан при создании перевода, но в его истории перевод появляется только сейчас
+		if err := tx.Model(&domain.User{}).Where("uuid = ?", transfer.SenderID).Update("info_version", gorm.Expr("info_version + 1")).Error; err != nil {
+			log.Error("Failed to update sender info version", zap.String("sender_id", transfer.SenderID))
+			return wrapError("failed to update sender balance", err)
+		}
+
+		transaction = domain.Transaction{
+			SenderID:   transfer.SenderID,
+			ReceiverID: transfer.ReceiverID,
+			Amount:     transfer.Amount,
+		}
+		if err := tx.Create(&transaction).Error; err != nil {
+			log.Error("Failed to create transaction", zap.String("sender_id", transfer.SenderID), zap.String("receiver_id", transfer.ReceiverID))
+			return wrapError("failed to create transaction record", err)
+		}
+
+		return r.setTransferStatus(tx, log, transfer.UUID, domain.TransferStatusAccepted)
+	}); err != nil {
+		return domain.Transaction{}, err
 	}
+	r.router.MarkWritten(transaction.ReceiverID, transaction.SenderID)
 
-	logger.DBLogger.Info("Item successfully purchased", zap.String("request_id", requestID), zap.String("item_name", itemName), zap.String("user_id", userID))
+	log.Info("Pending transfer accepted", zap.String("transfer_id", transferID))
+	return transaction, nil
+}
+
+func (r *merchRepository) DeclinePendingTransfer(ctx context.Context, userID string, transferID string) (domain.PendingTransfer, error) {
+	log := logger.FromContext(ctx, r.logger)
+	log.Info("DeclinePendingTransfer called", zap.String("transfer_id", transferID))
+
+	var declined domain.PendingTransfer
+	if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transfer, err := r.lockIncomingTransfer(tx, log, userID, transferID)
+		if err != nil {
+			return err
+		}
+		if err := r.refundPendingTransfer(tx, log, transfer, domain.TransferStatusDeclined); err != nil {
+			return err
+		}
+		declined = *transfer
+		return nil
+	}); err != nil {
+		return domain.PendingTransfer{}, err
+	}
+	r.router.MarkWritten(declined.ReceiverID, declined.SenderID)
+
+	log.Info("Pending transfer declined", zap.String("transfer_id", transferID))
+	return declined, nil
+}
+
+// ExpirePendingTransfers возвращает истёкшие переводы, чтобы вызывающий знал затронутых пользователей
+func (r *merchRepository) ExpirePendingTransfers(ctx context.Context, now time.Time) ([]domain.PendingTransfer, error) {
+	log := logger.FromContext(ctx, r.logger)
+
+	var transfers []domain.PendingTransfer
+	if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// SKIP LOCKED позволяет нескольким экземплярам приложения не мешать друг другу
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND expires_at <= ?", domain.TransferStatusPending, now).
+			Find(&transfers).Error; err != nil {
+			log.Error("Failed to get expired transfers", zap.Error(err))
+			return wrapError("failed to fetch expired transfers", err)
+		}
+
+		for i := range transfers {
+			if err := r.refundPendingTransfer(tx, log, &transfers[i], domain.TransferStatusExpired); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, t := range transfers {
+		r.router.MarkWritten(t.SenderID, t.ReceiverID)
+	}
+	if len(transfers) > 0 {
+		log.Info("Pending transfers expired", zap.Int("count", len(transfers)))
+	}
+	return transfers, nil
+}
+
+func (r *merchRepository) lockIncomingTransfer(tx *gorm.DB, log *zap.Logger, userID string, transferID string) (*domain.PendingTransfer, error) {
+	var transfer domain.PendingTransfer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("uuid = ? AND receiver_id = ?", transferID, userID).
+		First(&transfer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warn("Pending transfer not found", zap.String("transfer_id", transferID))
+			return nil, errors.New("transfer not found")
+		}
+		log.Error("Failed to get pending transfer", zap.Error(err))
+		return nil, wrapError("failed to fetch transfer", err)
+	}
+
+	if transfer.Status != domain.TransferStatusPending {
+		log.Warn("Transfer is not pending", zap.String("transfer_id", transferID), zap.String("status", transfer.Status))
+		return nil, errors.New("transfer is not pending")
+	}
+	if !transfer.ExpiresAt.After(time.Now()) {
+		log.Warn("Transfer has expired", zap.String("transfer_id", transferID))
+		return nil, errors.New("transfer has expired")
+	}
+
+	return &transfer, nil
+}
+
+func (r *merchRepository) refundPendingTransfer(tx *gorm.DB, log *zap.Logger, transfer *domain.PendingTransfer, status string) error {
+	if err := tx.Model(&domain.User{}).Where("uuid = ?", transfer.SenderID).Updates(balanceUpdate(gorm.Expr("coins + ?", transfer.Amount))).Error; err != nil {
+		log.Error("Failed to refund sender coins", zap.String("sender_id", transfer.SenderID))
+		return wrapError("failed to update sender balance", err)
+	}
+	return r.setTransferStatus(tx, log, transfer.UUID, status)
+}
+
+func (r *merchRepository) setTransferStatus(tx *gorm.DB, log *zap.Logger, transferID string, status string) error {
+	if err := tx.Model(&domain.PendingTransfer{}).Where("uuid = ?", transferID).Update("status", status).Error; err != nil {
+		log.Error("Failed to update transfer status", zap.String("transfer_id", transferID), zap.Error(err))
+		return wrapError("failed to update transfer status", err)
+	}
 	return nil
 }
+
+func (r *merchRepository) CreateScheduledTransfer(ctx context.Context, transfer *domain.ScheduledTransfer) error {
+	log := logger.FromContext(ctx, r.logger)
+	log.Info("CreateScheduledTransfer called", zap.String("sender_id", transfer.SenderID), zap.String("receiverID", transfer.ReceiverUsername))
+
+	var receiver domain.User
+	if err := r.db.WithContext(ctx).Select("uuid").Where("username = ?", transfer.ReceiverUsername).First(&receiver).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warn("User not found", zap.String("receiver_id", transfer.ReceiverUsername))
+			return errors.New("receiver not found")
+		}
+		log.Error("Failed to get user", zap.String("receiver_id", transfer.ReceiverUsername))
+		return wrapError("failed to find receiver", err)
+	}
+	// Перевод самому себе не выполнился бы ни разу, поэтому отклоняется при создании
+	if receiver.UUID == transfer.SenderID {
+		log.Warn("Scheduled transfer to self", zap.String("sender_id", transfer.SenderID))
+		return errors.New("cannot schedule transfer to yourself")
+	}
+
+	if err := r.db.WithContext(ctx).Create(transfer).Error; err != nil {
+		log.Error("Failed to create scheduled transfer", zap.Error(err))
+		return wrapError("failed to create scheduled transfer", err)
+	}
+	r.router.MarkWritten(transfer.SenderID)
+
+	log.Info("Scheduled transfer created", zap.String("transfer_id", transfer.UUID))
+	return nil
+}
+
+func (r *merchRepository) GetScheduledTransfers(ctx context.Context, senderID string) ([]domain.ScheduledTransfer, error) {
+	log := logger.FromContext(ctx, r.logger)
+	log.Info("GetScheduledTransfers called", zap.String("sender_id", senderID))
+
+	transfers := make([]domain.ScheduledTransfer, 0)
+	if err := r.router.Read(senderID).WithContext(ctx).Where("sender_id = ? AND status = ?", senderID, domain.ScheduleStatusActive).
+		Order("next_run_at").
+		Find(&transfers).Error; err != nil {
+		log.Error("Failed to get scheduled transfers", zap.Error(err))
+		return nil, wrapError("failed to fetch scheduled transfers", err)
+	}
+	return transfers, nil
+}
+
+func (r *merchRepository) CancelScheduledTransfer(ctx context.Context, senderID string, transferID string) error {
+	log := logger.FromContext(ctx, r.logger)
+	log.Info("CancelScheduledTransfer called", zap.String("sender_id", senderID), zap.String("transfer_id", transferID))
+
+	result := r.db.WithContext(ctx).Model(&domain.ScheduledTransfer{}).
+		Where("uuid = ? AND sender_id = ? AND status = ?", transferID, senderID, domain.ScheduleStatusActive).
+		Update("status", domain.ScheduleStatusCancelled)
+	if result.Error != nil {
+		log.Error("Failed to cancel scheduled transfer", zap.Error(result.Error))
+		return wrapError("failed to cancel scheduled transfer", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		log.Warn("Scheduled transfer not found", zap.String("transfer_id", transferID))
+		return errors.New("scheduled transfer not found")
+	}
+	r.router.MarkWritten(senderID)
+	return nil
+}
+
+// ClaimDueScheduledTransfers захватывает готовые к выполнению переводы на время lease,
+// чтобы другие экземпляры приложения не выполнили их повторно
+func (r *merchRepository) ClaimDueScheduledTransfers(ctx context.Context, now time.Time, lease time.Duration) ([]domain.ScheduledTransfer, error) {
+	log := logger.FromContext(ctx, r.logger)
+
+	var transfers []domain.ScheduledTransfer
+	if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_run_at <= ? AND (locked_until IS NULL OR locked_until < ?)", domain.ScheduleStatusActive, now, now).
+			Find(&transfers).Error; err != nil {
+			log.Error("Failed to get due scheduled transfers", zap.Error(err))
+			return wrapError("failed to fetch scheduled transfers", err)
+		}
+		if len(transfers) == 0 {
+			return nil
+		}
+
+		ids := make([]string, len(transfers))
+		for i, t := range transfers {
+			ids[i] = t.UUID
+		}
+		if err := tx.Model(&domain.ScheduledTransfer{}).Where("uuid IN ?", ids).Update("locked_until", now.Add(lease)).Error; err != nil {
+			log.Error("Failed to lock scheduled transfers", zap.Error(err))
+			return wrapError("failed to lock scheduled transfers", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// RunScheduledTransfer выполняет захваченный запуск и записывает его итог в одной транзакции, поэтому запуск
+// не повторяется, даже если процесс упал сразу после перевода. Строка расписания блокируется, и запуск
+// пропускается, если его уже выполнил другой экземпляр после истечения аренды. Ошибка перевода
+// (например, нехватка монет) не возвращается, а записывается в transfer.LastError и result.Error
+func (r *merchRepository) RunScheduledTransfer(ctx context.Context, transfer *domain.ScheduledTransfer, ranAt time.Time) (domain.ScheduledRunResult, error) {
+	log := logger.FromContext(ctx, r.logger)
+
+	result := domain.ScheduledRunResult{Outcome: domain.ScheduledRunSkipped}
+	if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var due []domain.ScheduledTransfer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uuid = ? AND status = ? AND next_run_at <= ?", transfer.UUID, domain.ScheduleStatusActive, ranAt).
+			Find(&due).Error; err != nil {
+			log.Error("Failed to lock scheduled transfer", zap.String("transfer_id", transfer.UUID), zap.Error(err))
+			return wrapError("failed to fetch scheduled transfer", err)
+		}
+		if len(due) == 0 {
+			log.Warn("Scheduled transfer already run", zap.String("transfer_id", transfer.UUID))
+			return nil
+		}
+
+		// Неудачный перевод откатывается до точки сохранения, а его итог всё равно записывается
+		var transaction domain.Transaction
+		runErr := tx.Transaction(func(tx *gorm.DB) error {
+			var err error
+			transaction, err = r.transferCoins(tx, log, transfer.SenderID, transfer.ReceiverUsername, transfer.Amount)
+			return err
+		})
+		transfer.RecordRun(ranAt, runErr)
+		if runErr != nil {
+			result = domain.ScheduledRunResult{Outcome: domain.ScheduledRunFailed, Error: runErr.Error()}
+		} else {
+			result = domain.ScheduledRunResult{Outcome: domain.ScheduledRunDone, Transaction: transaction}
+		}
+
+		if err := tx.Model(&domain.ScheduledTransfer{}).Where("uuid = ?", transfer.UUID).Updates(map[string]interface{}{
+			"status":       transfer.Status,
+			"next_run_at":  transfer.NextRunAt,
+			"last_run_at":  transfer.LastRunAt,
+			"last_error":   transfer.LastError,
+			"locked_until": nil,
+		}).Error; err != nil {
+			log.Error("Failed to update scheduled transfer", zap.String("transfer_id", transfer.UUID), zap.Error(err))
+			return wrapError("failed to update scheduled transfer", err)
+		}
+		return nil
+	}); err != nil {
+		return domain.ScheduledRunResult{}, err
+	}
+	r.router.MarkWritten(transfer.SenderID, result.Transaction.ReceiverID)
+	return result, nil
+}
+
+// GetActivePriceSchedule возвращает самую низкую действующую цену товара или nil, если распродажи нет.
+// По ней списываются монеты при покупке, поэтому она читается из primary
+func (r *merchRepository) GetActivePriceSchedule(ctx context.Context, itemName string, now time.Time) (*domain.PriceSchedule, error) {
+	log := logger.FromContext(ctx, r.logger)
+
+	var schedules []domain.PriceSchedule
+	if err := r.db.WithContext(ctx).Where("item_name = ? AND starts_at <= ? AND ends_at > ?", itemName, now, now).
+		Order("price").
+		Limit(1).
+		Find(&schedules).Error; err != nil {
+		log.Error("Failed to get price schedule", zap.String("item_name", itemName), zap.Error(err))
+		return nil, wrapError("failed to fetch price schedule", err)
+	}
+	if len(schedules) == 0 {
+		return nil, nil
+	}
+	return &schedules[0], nil
+}
+
+func (r *merchRepository) GetActivePriceSchedules(ctx context.Context, now time.Time) ([]domain.PriceSchedule, error) {
+	log := logger.FromContext(ctx, r.logger)
+
+	var schedules []domain.PriceSchedule
+	if err := r.router.Read().WithContext(ctx).Where("starts_at <= ? AND ends_at > ?", now, now).
+		Order("item_name, price").
+		Find(&schedules).Error; err != nil {
+		log.Error("Failed to get price schedules", zap.Error(err))
+		return nil, wrapError("failed to fetch price schedule", err)
+	}
+	return schedules, nil
+}
+
+// GetLeaderboard строит рейтинг по переводам начиная с since (nil - за всё время).
+// received - сумма полученных монет, thanked - число разных пользователей, которым отправлялись монеты.
+func (r *merchRepository) GetLeaderboard(ctx context.Context, category string, since *time.Time, limit int) ([]domain.LeaderboardEntry, error) {
+	log := logger.FromContext(ctx, r.logger)
+	log.Info("GetLeaderboard called", zap.String("category", category), zap.Int("limit", limit))
+
+	query := r.router.Read().WithContext(ctx).Table("transactions")
+	switch category {
+	case domain.LeaderboardReceived:
+		query = query.
+			Select("users.username, SUM(transactions.amount) AS score").
+			Joins("JOIN users ON transactions.receiver_id = users.uuid")
+	case domain.LeaderboardThanked:
+		query = query.
+			Select("users.username, COUNT(DISTINCT transactions.receiver_id) AS score").
+			Joins("JOIN users ON transactions.sender_id = users.uuid")
+	default:
+		return nil, errors.New("invalid leaderboard category")
+	}
+	// Переводы с неизвестным временем (created_at IS NULL) условию не удовлетворяют и учитываются только за всё время
+	if since != nil {
+		query = query.Where("transactions.created_at >= ?", *since)
+	}
+
+	entries := make([]domain.LeaderboardEntry, 0, limit)
+	if err := query.
+		Group("users.uuid, users.username").
+		Order("score DESC, users.username").
+		Limit(limit).
+		Scan(&entries).Error; err != nil {
+		log.Error("Failed to get leaderboard", zap.Error(err))
+		return nil, wrapError("failed to fetch leaderboard", err)
+	}
+	return entries, nil
+}
+
+// balanceUpdate меняет баланс и увеличивает версию данных пользователя, по которой строится ETag /api/info.
+// Через него проходят все изменения баланса и инвентаря
+func balanceUpdate(coins interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"coins":        coins,
+		"info_version": gorm.Expr("info_version + 1"),
+	}
+}
+
+// repositoryError сохраняет текст, по которому обработчики выбирают код ответа, и причину:
+// по ней определяются истёкший срок запроса и statement_timeout
+type repositoryError struct {
+	message string
+	cause   error
+}
+
+func (e *repositoryError) Error() string {
+	return e.message
+}
+
+func (e *repositoryError) Unwrap() error {
+	return e.cause
+}
+
+func wrapError(message string, cause error) error {
+	return &repositoryError{message: message, cause: cause}
+}
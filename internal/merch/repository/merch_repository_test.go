@@ -2,7 +2,7 @@ package repository
 
 import (
 	"avito_staj_2025/domain"
-	"avito_staj_2025/internal/service/logger"
+	"avito_staj_2025/internal/service/dbrouter"
 	"context"
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
@@ -13,10 +13,10 @@ import (
 	"gorm.io/gorm"
 	"regexp"
 	"testing"
+	"time"
 )
 
 func TestSendCoins(t *testing.T) {
-	logger.DBLogger = zap.NewNop()
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
@@ -26,7 +26,7 @@ func TestSendCoins(t *testing.T) {
 	}), &gorm.Config{})
 	require.NoError(t, err)
 
-	repo := NewMerchRepository(gormDB)
+	repo := NewMerchRepository(gormDB, zap.NewNop())
 	ctx := context.Background()
 
 	senderID := "sender-uuid"
@@ -36,7 +36,7 @@ func TestSendCoins(t *testing.T) {
 	t.Run("Success - Send Coins", func(t *testing.T) {
 		mock.ExpectBegin()
 
-		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1 ORDER BY "users"."uuid" LIMIT \$2`).
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1 ORDER BY "users"."uuid" LIMIT \$2 FOR UPDATE`).
 			WithArgs(senderID, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"uuid", "coins"}).AddRow(senderID, 200))
 
@@ -44,39 +44,40 @@ func TestSendCoins(t *testing.T) {
 			WithArgs(receiverUsername, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"uuid", "username", "coins"}).AddRow("receiver-uuid", receiverUsername, 50))
 
-		mock.ExpectExec(`UPDATE \"users\" SET \"coins\"=\$1 WHERE uuid = \$2`).
+		mock.ExpectExec(`UPDATE \"users\" SET \"coins\"=\$1,\"info_version\"=info_version \+ 1 WHERE uuid = \$2`).
 			WithArgs(100, senderID).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec(`UPDATE \"users\" SET \"coins\"=\$1 WHERE uuid = \$2`).
-			WithArgs(150, "receiver-uuid").
+		mock.ExpectExec(`UPDATE \"users\" SET \"coins\"=coins \+ \$1,\"info_version\"=info_version \+ 1 WHERE uuid = \$2`).
+			WithArgs(amount, "receiver-uuid").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		mock.ExpectQuery(`INSERT INTO \"transactions\"`).
-			WithArgs(senderID, "receiver-uuid", amount).
+			WithArgs(senderID, "receiver-uuid", amount, sqlmock.AnyArg()).
 			WillReturnRows(sqlmock.NewRows([]string{"uuid"}).AddRow("transaction-uuid"))
 
 		mock.ExpectCommit()
 
-		err := repo.SendCoins(ctx, senderID, receiverUsername, amount)
+		transaction, err := repo.SendCoins(ctx, senderID, receiverUsername, amount)
 		assert.NoError(t, err)
+		assert.Equal(t, "transaction-uuid", transaction.UUID)
 	})
 
 	t.Run("Fail - Sender Not Found", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1 ORDER BY "users"."uuid" LIMIT \$2`).
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1 ORDER BY "users"."uuid" LIMIT \$2 FOR UPDATE`).
 			WithArgs(senderID, 1).
 			WillReturnError(gorm.ErrRecordNotFound)
 		mock.ExpectRollback()
 
-		err := repo.SendCoins(ctx, senderID, receiverUsername, amount)
+		_, err := repo.SendCoins(ctx, senderID, receiverUsername, amount)
 		assert.Error(t, err)
 		assert.Equal(t, "sender not found", err.Error())
 	})
 
 	t.Run("Fail - Not Enough Coins", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1 ORDER BY "users"."uuid" LIMIT \$2`).
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1 ORDER BY "users"."uuid" LIMIT \$2 FOR UPDATE`).
 			WithArgs(senderID, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"uuid", "coins"}).AddRow(senderID, 50))
 
@@ -85,15 +86,13 @@ func TestSendCoins(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"uuid", "coins"}).AddRow(receiverUsername, 100))
 		mock.ExpectRollback()
 
-		err := repo.SendCoins(ctx, senderID, receiverUsername, amount)
+		_, err := repo.SendCoins(ctx, senderID, receiverUsername, amount)
 		assert.Error(t, err)
 		assert.Equal(t, "not enough coins", err.Error())
 	})
 }
 
 func TestGetUserMerchInformation(t *testing.T) {
-	logger.DBLogger = zap.NewNop()
-
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
@@ -103,7 +102,7 @@ func TestGetUserMerchInformation(t *testing.T) {
 	}), &gorm.Config{})
 	require.NoError(t, err)
 
-	repo := NewMerchRepository(gormDB)
+	repo := NewMerchRepository(gormDB, zap.NewNop())
 	ctx := context.Background()
 	userID := "user-uuid"
 
@@ -131,7 +130,7 @@ func TestGetUserMerchInformation(t *testing.T) {
 
 		mock.ExpectCommit()
 
-		response, err := repo.GetUserMerchInformation(ctx, userID)
+		response, err := repo.GetUserMerchInformation(ctx, userID, 0)
 
 		assert.NoError(t, err)
 		assert.Equal(t, 500, response.Coins)
@@ -149,7 +148,7 @@ func TestGetUserMerchInformation(t *testing.T) {
 			WillReturnError(gorm.ErrRecordNotFound)
 		mock.ExpectRollback()
 
-		response, err := repo.GetUserMerchInformation(ctx, userID)
+		response, err := repo.GetUserMerchInformation(ctx, userID, 0)
 
 		assert.Error(t, err)
 		assert.Equal(t, "user not found", err.Error())
@@ -171,7 +170,7 @@ func TestGetUserMerchInformation(t *testing.T) {
 			WillReturnError(errors.New("database error"))
 		mock.ExpectRollback()
 
-		response, err := repo.GetUserMerchInformation(ctx, userID)
+		response, err := repo.GetUserMerchInformation(ctx, userID, 0)
 
 		assert.Error(t, err)
 		assert.Equal(t, "failed to fetch inventory", err.Error())
@@ -200,7 +199,7 @@ func TestGetUserMerchInformation(t *testing.T) {
 			WillReturnError(errors.New("database error"))
 		mock.ExpectRollback()
 
-		response, err := repo.GetUserMerchInformation(ctx, userID)
+		response, err := repo.GetUserMerchInformation(ctx, userID, 0)
 
 		assert.Error(t, err)
 		assert.Equal(t, "failed to fetch transactions", err.Error())
@@ -210,9 +209,44 @@ func TestGetUserMerchInformation(t *testing.T) {
 	})
 }
 
-func TestBuyItem(t *testing.T) {
-	logger.DBLogger = zap.NewNop()
+func TestGetUserInfoVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
 
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+
+	repo := NewMerchRepository(gormDB, zap.NewNop())
+	ctx := context.Background()
+	userID := "user-uuid"
+	query := regexp.QuoteMeta(`SELECT "info_version" FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2`)
+
+	t.Run("Success - Version Only", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs(userID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"info_version"}).AddRow(7))
+
+		version, err := repo.GetUserInfoVersion(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), version)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Fail - User Not Found", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs(userID, 1).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		_, err := repo.GetUserInfoVersion(ctx, userID)
+		assert.EqualError(t, err, "user not found")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestBuyItem(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
@@ -222,7 +256,7 @@ func TestBuyItem(t *testing.T) {
 	}), &gorm.Config{})
 	require.NoError(t, err)
 
-	repo := NewMerchRepository(gormDB)
+	repo := NewMerchRepository(gormDB, zap.NewNop())
 	ctx := context.Background()
 	userID := "user-uuid"
 	itemName := "pen"
@@ -232,11 +266,11 @@ func TestBuyItem(t *testing.T) {
 		userRows := sqlmock.NewRows([]string{"uuid", "coins"}).
 			AddRow(userID, 500)
 		mock.ExpectBegin()
-		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2`)).
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2 FOR UPDATE`)).
 			WithArgs(userID, 1).
 			WillReturnRows(userRows)
 
-		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "coins"=$1 WHERE uuid = $2`)).
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "coins"=$1,"info_version"=info_version + 1 WHERE uuid = $2`)).
 			WithArgs(490, userID).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -244,26 +278,157 @@ func TestBuyItem(t *testing.T) {
 			WithArgs(userID, itemName).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "purchases"`)).
+			WithArgs(userID, itemName, itemCost, itemCost, "", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid"}).AddRow("purchase-uuid"))
+
+		mock.ExpectCommit()
+
+		purchase, err := repo.BuyItem(ctx, userID, itemName, itemCost, itemCost, "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "purchase-uuid", purchase.UUID)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Success - Buy With Promo Code", func(t *testing.T) {
+		userRows := sqlmock.NewRows([]string{"uuid", "coins"}).
+			AddRow(userID, 500)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2 FOR UPDATE`)).
+			WithArgs(userID, 1).
+			WillReturnRows(userRows)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "promo_codes" WHERE code = $1 ORDER BY "promo_codes"."code" LIMIT $2 FOR UPDATE`)).
+			WithArgs("SALE50", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"code", "discount_type", "discount_value", "max_uses", "used_count"}).
+				AddRow("SALE50", domain.DiscountPercent, 50, 10, 3))
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "promo_codes" SET "used_count"=used_count + 1 WHERE code = $1`)).
+			WithArgs("SALE50").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "coins"=$1,"info_version"=info_version + 1 WHERE uuid = $2`)).
+			WithArgs(495, userID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO inventories (owner_id, item_name, item_amount) VALUES ($1, $2, 1) ON CONFLICT (owner_id, item_name) DO UPDATE SET item_amount = inventories.item_amount + 1`)).
+			WithArgs(userID, itemName).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "purchases"`)).
+			WithArgs(userID, itemName, itemCost, 5, "SALE50", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid"}).AddRow("purchase-uuid"))
+
 		mock.ExpectCommit()
 
-		err := repo.BuyItem(ctx, userID, itemName, itemCost)
+		_, err := repo.BuyItem(ctx, userID, itemName, itemCost, itemCost, "SALE50")
 
 		assert.NoError(t, err)
 
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("Success - Buy On Sale With Promo Code", func(t *testing.T) {
+		// Действующая распродажа снижает цену pen с 10 до 8, промокод применяется к цене распродажи
+		salePrice := 8
+		userRows := sqlmock.NewRows([]string{"uuid", "coins"}).
+			AddRow(userID, 500)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2 FOR UPDATE`)).
+			WithArgs(userID, 1).
+			WillReturnRows(userRows)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "promo_codes" WHERE code = $1 ORDER BY "promo_codes"."code" LIMIT $2 FOR UPDATE`)).
+			WithArgs("SALE50", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"code", "discount_type", "discount_value", "max_uses", "used_count"}).
+				AddRow("SALE50", domain.DiscountPercent, 50, 10, 3))
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "promo_codes" SET "used_count"=used_count + 1 WHERE code = $1`)).
+			WithArgs("SALE50").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "coins"=$1,"info_version"=info_version + 1 WHERE uuid = $2`)).
+			WithArgs(496, userID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO inventories (owner_id, item_name, item_amount) VALUES ($1, $2, 1) ON CONFLICT (owner_id, item_name) DO UPDATE SET item_amount = inventories.item_amount + 1`)).
+			WithArgs(userID, itemName).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "purchases" ("user_id","item_name","base_price","price","promo_code","created_at")`)).
+			WithArgs(userID, itemName, itemCost, 4, "SALE50", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid"}).AddRow("purchase-uuid"))
+
+		mock.ExpectCommit()
+
+		purchase, err := repo.BuyItem(ctx, userID, itemName, itemCost, salePrice, "SALE50")
+
+		assert.NoError(t, err)
+		assert.Equal(t, itemCost, purchase.BasePrice)
+		assert.Equal(t, 4, purchase.Price)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Fail - Promo Code Usage Limit Reached", func(t *testing.T) {
+		userRows := sqlmock.NewRows([]string{"uuid", "coins"}).
+			AddRow(userID, 500)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2 FOR UPDATE`)).
+			WithArgs(userID, 1).
+			WillReturnRows(userRows)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "promo_codes" WHERE code = $1 ORDER BY "promo_codes"."code" LIMIT $2 FOR UPDATE`)).
+			WithArgs("ONCE", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"code", "discount_type", "discount_value", "max_uses", "used_count"}).
+				AddRow("ONCE", domain.DiscountFixed, 5, 1, 1))
+
+		mock.ExpectRollback()
+
+		_, err := repo.BuyItem(ctx, userID, itemName, itemCost, itemCost, "ONCE")
+
+		assert.Error(t, err)
+		assert.Equal(t, "promo code usage limit reached", err.Error())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Fail - Promo Code For Other Item", func(t *testing.T) {
+		userRows := sqlmock.NewRows([]string{"uuid", "coins"}).
+			AddRow(userID, 500)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2 FOR UPDATE`)).
+			WithArgs(userID, 1).
+			WillReturnRows(userRows)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "promo_codes" WHERE code = $1 ORDER BY "promo_codes"."code" LIMIT $2 FOR UPDATE`)).
+			WithArgs("HOODY", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"code", "discount_type", "discount_value", "item_name"}).
+				AddRow("HOODY", domain.DiscountPercent, 20, "hoody"))
+
+		mock.ExpectRollback()
+
+		_, err := repo.BuyItem(ctx, userID, itemName, itemCost, itemCost, "HOODY")
+
+		assert.Error(t, err)
+		assert.Equal(t, "promo code not applicable", err.Error())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("Fail - Not Enough Coins", func(t *testing.T) {
 		userRows := sqlmock.NewRows([]string{"uuid", "coins"}).
 			AddRow(userID, 8)
 		mock.ExpectBegin()
-		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2`)).
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2 FOR UPDATE`)).
 			WithArgs(userID, 1).
 			WillReturnRows(userRows)
 
 		mock.ExpectRollback()
 
-		err := repo.BuyItem(ctx, userID, itemName, itemCost)
+		_, err := repo.BuyItem(ctx, userID, itemName, itemCost, itemCost, "")
 
 		assert.Error(t, err)
 		assert.Equal(t, "not enough coins", err.Error())
@@ -273,13 +438,13 @@ func TestBuyItem(t *testing.T) {
 
 	t.Run("Fail - User Not Found", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2`)).
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2 FOR UPDATE`)).
 			WithArgs(userID, 1).
 			WillReturnError(gorm.ErrRecordNotFound)
 
 		mock.ExpectRollback()
 
-		err := repo.BuyItem(ctx, userID, itemName, itemCost)
+		_, err := repo.BuyItem(ctx, userID, itemName, itemCost, itemCost, "")
 
 		assert.Error(t, err)
 		assert.Equal(t, "user not found", err.Error())
@@ -291,17 +456,17 @@ func TestBuyItem(t *testing.T) {
 		userRows := sqlmock.NewRows([]string{"uuid", "coins"}).
 			AddRow(userID, 500)
 		mock.ExpectBegin()
-		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2`)).
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2 FOR UPDATE`)).
 			WithArgs(userID, 1).
 			WillReturnRows(userRows)
 
-		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "coins"=$1 WHERE uuid = $2`)).
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "coins"=$1,"info_version"=info_version + 1 WHERE uuid = $2`)).
 			WithArgs(490, userID).
 			WillReturnError(errors.New("database error"))
 
 		mock.ExpectRollback()
 
-		err := repo.BuyItem(ctx, userID, itemName, itemCost)
+		_, err := repo.BuyItem(ctx, userID, itemName, itemCost, itemCost, "")
 
 		assert.Error(t, err)
 		assert.Equal(t, "failed to update user balance", err.Error())
@@ -313,11 +478,11 @@ func TestBuyItem(t *testing.T) {
 		userRows := sqlmock.NewRows([]string{"uuid", "coins"}).
 			AddRow(userID, 500)
 		mock.ExpectBegin()
-		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2`)).
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2 FOR UPDATE`)).
 			WithArgs(userID, 1).
 			WillReturnRows(userRows)
 
-		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "coins"=$1 WHERE uuid = $2`)).
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "coins"=$1,"info_version"=info_version + 1 WHERE uuid = $2`)).
 			WithArgs(490, userID).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -327,7 +492,7 @@ func TestBuyItem(t *testing.T) {
 
 		mock.ExpectRollback()
 
-		err := repo.BuyItem(ctx, userID, itemName, itemCost)
+		_, err := repo.BuyItem(ctx, userID, itemName, itemCost, itemCost, "")
 
 		assert.Error(t, err)
 		assert.Equal(t, "failed to update inventory", err.Error())
@@ -335,3 +500,471 @@ func TestBuyItem(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestPendingTransfers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+
+	repo := NewMerchRepository(gormDB, zap.NewNop())
+	ctx := context.Background()
+	senderID := "sender-uuid"
+	receiverID := "receiver-uuid"
+	transferID := "transfer-uuid"
+	expiresAt := time.Now().Add(time.Hour)
+
+	t.Run("Success - Create Pending Transfer", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2 FOR UPDATE`)).
+			WithArgs(senderID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "coins"}).AddRow(senderID, 200))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE username = $1 ORDER BY "users"."uuid" LIMIT $2`)).
+			WithArgs("receiverUser", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "username", "coins"}).AddRow(receiverID, "receiverUser", 50))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "coins"=$1,"info_version"=info_version + 1 WHERE uuid = $2`)).
+			WithArgs(100, senderID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(`INSERT INTO "pending_transfers"`).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid"}).AddRow(transferID))
+		mock.ExpectCommit()
+
+		id, err := repo.CreatePendingTransfer(ctx, senderID, "receiverUser", 100, expiresAt)
+
+		assert.NoError(t, err)
+		assert.Equal(t, transferID, id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Fail - Create Pending Transfer Not Enough Coins", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2 FOR UPDATE`)).
+			WithArgs(senderID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "coins"}).AddRow(senderID, 50))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE username = $1 ORDER BY "users"."uuid" LIMIT $2`)).
+			WithArgs("receiverUser", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "username", "coins"}).AddRow(receiverID, "receiverUser", 50))
+		mock.ExpectRollback()
+
+		_, err := repo.CreatePendingTransfer(ctx, senderID, "receiverUser", 100, expiresAt)
+
+		assert.Error(t, err)
+		assert.Equal(t, "not enough coins", err.Error())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Success - Accept Pending Transfer", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "pending_transfers" WHERE uuid = $1 AND receiver_id = $2 ORDER BY "pending_transfers"."uuid" LIMIT $3 FOR UPDATE`)).
+			WithArgs(transferID, receiverID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "sender_id", "receiver_id", "amount", "status", "expires_at"}).
+				AddRow(transferID, senderID, receiverID, 100, domain.TransferStatusPending, expiresAt))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "coins"=coins + $1,"info_version"=info_version + 1 WHERE uuid = $2`)).
+			WithArgs(100, receiverID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "info_version"=info_version + 1 WHERE uuid = $1`)).
+			WithArgs(senderID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(`INSERT INTO "transactions"`).
+			WithArgs(senderID, receiverID, 100, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid"}).AddRow("transaction-uuid"))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "pending_transfers" SET "status"=$1 WHERE uuid = $2`)).
+			WithArgs(domain.TransferStatusAccepted, transferID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		transaction, err := repo.AcceptPendingTransfer(ctx, receiverID, transferID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "transaction-uuid", transaction.UUID)
+		assert.Equal(t, senderID, transaction.SenderID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Fail - Accept Already Declined Transfer", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "pending_transfers" WHERE uuid = $1 AND receiver_id = $2 ORDER BY "pending_transfers"."uuid" LIMIT $3 FOR UPDATE`)).
+			WithArgs(transferID, receiverID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "sender_id", "receiver_id", "amount", "status", "expires_at"}).
+				AddRow(transferID, senderID, receiverID, 100, domain.TransferStatusDeclined, expiresAt))
+		mock.ExpectRollback()
+
+		_, err := repo.AcceptPendingTransfer(ctx, receiverID, transferID)
+
+		assert.Error(t, err)
+		assert.Equal(t, "transfer is not pending", err.Error())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Fail - Decline Transfer Not Found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "pending_transfers" WHERE uuid = $1 AND receiver_id = $2 ORDER BY "pending_transfers"."uuid" LIMIT $3 FOR UPDATE`)).
+			WithArgs(transferID, senderID, 1).
+			WillReturnError(gorm.ErrRecordNotFound)
+		mock.ExpectRollback()
+
+		_, err := repo.DeclinePendingTransfer(ctx, senderID, transferID)
+
+		assert.Error(t, err)
+		assert.Equal(t, "transfer not found", err.Error())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Success - Expire Pending Transfers", func(t *testing.T) {
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "pending_transfers" WHERE status = $1 AND expires_at <= $2 FOR UPDATE SKIP LOCKED`)).
+			WithArgs(domain.TransferStatusPending, now).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "sender_id", "receiver_id", "amount", "status", "expires_at"}).
+				AddRow(transferID, senderID, receiverID, 100, domain.TransferStatusPending, now.Add(-time.Minute)))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "coins"=coins + $1,"info_version"=info_version + 1 WHERE uuid = $2`)).
+			WithArgs(100, senderID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "pending_transfers" SET "status"=$1 WHERE uuid = $2`)).
+			WithArgs(domain.TransferStatusExpired, transferID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		expired, err := repo.ExpirePendingTransfers(ctx, now)
+
+		assert.NoError(t, err)
+		require.Len(t, expired, 1)
+		assert.Equal(t, senderID, expired[0].SenderID)
+		assert.Equal(t, receiverID, expired[0].ReceiverID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestScheduledTransfers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+
+	repo := NewMerchRepository(gormDB, zap.NewNop())
+	ctx := context.Background()
+	senderID := "sender-uuid"
+	transferID := "transfer-uuid"
+
+	t.Run("Fail - Create For Unknown Receiver", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "uuid" FROM "users" WHERE username = $1 ORDER BY "users"."uuid" LIMIT $2`)).
+			WithArgs("ghost", 1).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		err := repo.CreateScheduledTransfer(ctx, &domain.ScheduledTransfer{SenderID: senderID, ReceiverUsername: "ghost", Amount: 10})
+
+		assert.Error(t, err)
+		assert.Equal(t, "receiver not found", err.Error())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Fail - Create For Self", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "uuid" FROM "users" WHERE username = $1 ORDER BY "users"."uuid" LIMIT $2`)).
+			WithArgs("me", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid"}).AddRow(senderID))
+
+		err := repo.CreateScheduledTransfer(ctx, &domain.ScheduledTransfer{SenderID: senderID, ReceiverUsername: "me", Amount: 10, Schedule: "@daily"})
+
+		assert.Error(t, err)
+		assert.Equal(t, "cannot schedule transfer to yourself", err.Error())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Fail - Cancel Unknown Transfer", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "scheduled_transfers" SET "status"=$1 WHERE uuid = $2 AND sender_id = $3 AND status = $4`)).
+			WithArgs(domain.ScheduleStatusCancelled, transferID, senderID, domain.ScheduleStatusActive).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := repo.CancelScheduledTransfer(ctx, senderID, transferID)
+
+		assert.Error(t, err)
+		assert.Equal(t, "scheduled transfer not found", err.Error())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Success - Claim Due Transfers", func(t *testing.T) {
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "scheduled_transfers" WHERE status = $1 AND next_run_at <= $2 AND (locked_until IS NULL OR locked_until < $3) FOR UPDATE SKIP LOCKED`)).
+			WithArgs(domain.ScheduleStatusActive, now, now).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "sender_id", "receiver_username", "amount", "status", "next_run_at"}).
+				AddRow(transferID, senderID, "receiverUser", 10, domain.ScheduleStatusActive, now))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "scheduled_transfers" SET "locked_until"=$1 WHERE uuid IN ($2)`)).
+			WithArgs(now.Add(time.Minute), transferID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		transfers, err := repo.ClaimDueScheduledTransfers(ctx, now, time.Minute)
+
+		assert.NoError(t, err)
+		assert.Len(t, transfers, 1)
+		assert.Equal(t, "receiverUser", transfers[0].ReceiverUsername)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	lockDue := regexp.QuoteMeta(`SELECT * FROM "scheduled_transfers" WHERE uuid = $1 AND status = $2 AND next_run_at <= $3 FOR UPDATE`)
+	lockSender := regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2 FOR UPDATE`)
+	finishRun := regexp.QuoteMeta(`UPDATE "scheduled_transfers" SET "last_error"=$1,"last_run_at"=$2,"locked_until"=$3,"next_run_at"=$4,"status"=$5 WHERE uuid = $6`)
+
+	t.Run("Success - Run Recorded With Transfer", func(t *testing.T) {
+		ranAt := time.Now()
+		transfer := &domain.ScheduledTransfer{UUID: transferID, SenderID: senderID, ReceiverUsername: "receiverUser", Amount: 10, Status: domain.ScheduleStatusActive, NextRunAt: ranAt}
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockDue).
+			WithArgs(transferID, domain.ScheduleStatusActive, ranAt).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid"}).AddRow(transferID))
+		mock.ExpectExec(`SAVEPOINT`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(lockSender).
+			WithArgs(senderID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "coins"}).AddRow(senderID, 100))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE username = $1`)).
+			WithArgs("receiverUser", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "coins"}).AddRow("receiver-uuid", 0))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "coins"=$1,"info_version"=info_version + 1 WHERE uuid = $2`)).
+			WithArgs(90, senderID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "coins"=coins + $1,"info_version"=info_version + 1 WHERE uuid = $2`)).
+			WithArgs(10, "receiver-uuid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "transactions"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid"}).AddRow("transaction-uuid"))
+		mock.ExpectExec(finishRun).
+			WithArgs("", sqlmock.AnyArg(), nil, ranAt, domain.ScheduleStatusCompleted, transferID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		result, err := repo.RunScheduledTransfer(ctx, transfer, ranAt)
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.ScheduledRunDone, result.Outcome)
+		assert.Equal(t, "transaction-uuid", result.Transaction.UUID)
+		assert.Equal(t, domain.ScheduleStatusCompleted, transfer.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Success - Failed Run Recorded Without Transfer", func(t *testing.T) {
+		ranAt := time.Now()
+		transfer := &domain.ScheduledTransfer{UUID: transferID, SenderID: senderID, ReceiverUsername: "receiverUser", Amount: 10, Schedule: "@daily", Status: domain.ScheduleStatusActive, NextRunAt: ranAt}
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockDue).
+			WithArgs(transferID, domain.ScheduleStatusActive, ranAt).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid"}).AddRow(transferID))
+		mock.ExpectExec(`SAVEPOINT`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(lockSender).
+			WithArgs(senderID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "coins"}).AddRow(senderID, 5))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE username = $1`)).
+			WithArgs("receiverUser", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "coins"}).AddRow("receiver-uuid", 0))
+		mock.ExpectExec(`ROLLBACK TO SAVEPOINT`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(finishRun).
+			WithArgs("not enough coins", sqlmock.AnyArg(), nil, sqlmock.AnyArg(), domain.ScheduleStatusActive, transferID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		result, err := repo.RunScheduledTransfer(ctx, transfer, ranAt)
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.ScheduledRunFailed, result.Outcome)
+		assert.Empty(t, result.Transaction.UUID)
+		assert.Equal(t, "not enough coins", result.Error)
+		assert.Equal(t, "not enough coins", transfer.LastError)
+		assert.True(t, transfer.NextRunAt.After(ranAt))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Success - Run Already Done By Another Instance", func(t *testing.T) {
+		ranAt := time.Now()
+		// Ошибка предыдущего запуска остаётся в захваченной строке, но к этому запуску не относится
+		transfer := &domain.ScheduledTransfer{UUID: transferID, SenderID: senderID, ReceiverUsername: "receiverUser", Amount: 10, Status: domain.ScheduleStatusActive, NextRunAt: ranAt, LastError: "not enough coins"}
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockDue).
+			WithArgs(transferID, domain.ScheduleStatusActive, ranAt).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid"}))
+		mock.ExpectCommit()
+
+		result, err := repo.RunScheduledTransfer(ctx, transfer, ranAt)
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.ScheduledRunSkipped, result.Outcome)
+		assert.Empty(t, result.Error)
+		assert.Nil(t, transfer.LastRunAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPriceSchedules(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+
+	repo := NewMerchRepository(gormDB, zap.NewNop())
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("Success - Active Sale", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "price_schedules" WHERE item_name = $1 AND starts_at <= $2 AND ends_at > $3 ORDER BY price LIMIT $4`)).
+			WithArgs("hoody", now, now, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "item_name", "price", "starts_at", "ends_at"}).
+				AddRow(1, "hoody", 200, now.Add(-time.Hour), now.Add(time.Hour)))
+
+		schedule, err := repo.GetActivePriceSchedule(ctx, "hoody", now)
+
+		assert.NoError(t, err)
+		require.NotNil(t, schedule)
+		assert.Equal(t, 200, schedule.Price)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Success - No Sale", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "price_schedules" WHERE item_name = $1 AND starts_at <= $2 AND ends_at > $3 ORDER BY price LIMIT $4`)).
+			WithArgs("pen", now, now, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "item_name", "price", "starts_at", "ends_at"}))
+
+		schedule, err := repo.GetActivePriceSchedule(ctx, "pen", now)
+
+		assert.NoError(t, err)
+		assert.Nil(t, schedule)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Fail - Database Error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "price_schedules" WHERE starts_at <= $1 AND ends_at > $2 ORDER BY item_name, price`)).
+			WithArgs(now, now).
+			WillReturnError(errors.New("database error"))
+
+		_, err := repo.GetActivePriceSchedules(ctx, now)
+
+		assert.Error(t, err)
+		assert.Equal(t, "failed to fetch price schedule", err.Error())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetLeaderboard(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+
+	repo := NewMerchRepository(gormDB, zap.NewNop())
+	ctx := context.Background()
+
+	t.Run("Success - Top Receivers For Window", func(t *testing.T) {
+		since := time.Now().AddDate(0, 0, -7)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT users.username, SUM(transactions.amount) AS score FROM "transactions" JOIN users ON transactions.receiver_id = users.uuid WHERE transactions.created_at >= $1 GROUP BY users.uuid, users.username ORDER BY score DESC, users.username LIMIT $2`)).
+			WithArgs(since, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"username", "score"}).
+				AddRow("Alice", 300).
+				AddRow("Bob", 100))
+
+		entries, err := repo.GetLeaderboard(ctx, domain.LeaderboardReceived, &since, 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.LeaderboardEntry{{Username: "Alice", Score: 300}, {Username: "Bob", Score: 100}}, entries)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Success - Most Thanked All Time", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT users.username, COUNT(DISTINCT transactions.receiver_id) AS score FROM "transactions" JOIN users ON transactions.sender_id = users.uuid GROUP BY users.uuid, users.username ORDER BY score DESC, users.username LIMIT $1`)).
+			WithArgs(5).
+			WillReturnRows(sqlmock.NewRows([]string{"username", "score"}).AddRow("Carol", 4))
+
+		entries, err := repo.GetLeaderboard(ctx, domain.LeaderboardThanked, nil, 5)
+
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestReadReplicaRouting(t *testing.T) {
+	newMockDB := func(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+
+		gormDB, err := gorm.Open(postgres.New(postgres.Config{
+			Conn: db,
+		}), &gorm.Config{})
+		require.NoError(t, err)
+		return gormDB, mock
+	}
+	primaryDB, primary := newMockDB(t)
+	replicaDB, replica := newMockDB(t)
+
+	repo := NewRoutedMerchRepository(dbrouter.New(primaryDB, []*gorm.DB{replicaDB}, time.Minute), zap.NewNop())
+	ctx := context.Background()
+	userID := "user-uuid"
+	versionQuery := regexp.QuoteMeta(`SELECT "info_version" FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2`)
+
+	t.Run("Success - Reads Served By Replica", func(t *testing.T) {
+		replica.ExpectQuery(versionQuery).
+			WithArgs(userID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"info_version"}).AddRow(1))
+		replica.ExpectQuery(regexp.QuoteMeta(`SELECT users.username, COUNT(DISTINCT transactions.receiver_id) AS score FROM "transactions"`)).
+			WithArgs(5).
+			WillReturnRows(sqlmock.NewRows([]string{"username", "score"}))
+
+		_, err := repo.GetUserInfoVersion(ctx, userID)
+		require.NoError(t, err)
+		_, err = repo.GetLeaderboard(ctx, domain.LeaderboardThanked, nil, 5)
+		require.NoError(t, err)
+
+		assert.NoError(t, replica.ExpectationsWereMet())
+		assert.NoError(t, primary.ExpectationsWereMet())
+	})
+
+	t.Run("Success - Buyer Reads Own Writes From Primary", func(t *testing.T) {
+		primary.ExpectBegin()
+		primary.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE uuid = $1 ORDER BY "users"."uuid" LIMIT $2 FOR UPDATE`)).
+			WithArgs(userID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "coins"}).AddRow(userID, 500))
+		primary.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "coins"=$1,"info_version"=info_version + 1 WHERE uuid = $2`)).
+			WithArgs(490, userID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		primary.ExpectExec(regexp.QuoteMeta(`INSERT INTO inventories`)).
+			WithArgs(userID, "pen").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		primary.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "purchases"`)).
+			WithArgs(userID, "pen", 10, 10, "", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid"}).AddRow("purchase-uuid"))
+		primary.ExpectCommit()
+		primary.ExpectQuery(versionQuery).
+			WithArgs(userID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"info_version"}).AddRow(2))
+		replica.ExpectQuery(versionQuery).
+			WithArgs("other-uuid", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"info_version"}).AddRow(5))
+
+		_, err := repo.BuyItem(ctx, userID, "pen", 10, 10, "")
+		require.NoError(t, err)
+		version, err := repo.GetUserInfoVersion(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), version)
+		_, err = repo.GetUserInfoVersion(ctx, "other-uuid")
+		require.NoError(t, err)
+
+		assert.NoError(t, primary.ExpectationsWereMet())
+		assert.NoError(t, replica.ExpectationsWereMet())
+	})
+}
@@ -2,101 +2,425 @@ package usecase
 
 import (
 	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/config"
 	"avito_staj_2025/internal/service/logger"
-	"avito_staj_2025/internal/service/middleware"
+	"avito_staj_2025/internal/service/metrics"
 	"context"
 	"errors"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	maxPromoCodeLen = 50
+	// maxScheduleLen совпадает с размером колонки scheduled_transfers.schedule
+	maxScheduleLen = 100
+
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 100
 )
 
 type MerchUsecase interface {
-	SendCoins(ctx context.Context, senderID string, receiverUsername string, amount int) error
-	GetUserMerchInformation(ctx context.Context, userID string) (domain.UserInformationResponse, error)
-	BuyItem(ctx context.Context, userID string, itemName string) error
+	SendCoins(ctx context.Context, senderID string, receiverUsername string, amount int) (domain.Transaction, error)
+	GetUserMerchInformation(ctx context.Context, userID string, minVersion int64) (domain.UserInformationResponse, error)
+	GetUserInfoVersion(ctx context.Context, userID string) (int64, error)
+	BuyItem(ctx context.Context, userID string, itemName string, promoCode string) (domain.Purchase, error)
+	GetCatalog(ctx context.Context) ([]domain.CatalogItem, error)
+	GetLeaderboard(ctx context.Context, category string, window string, limit int) (domain.LeaderboardResponse, error)
+	SendCoinsPending(ctx context.Context, senderID string, receiverUsername string, amount int) (string, error)
+	GetPendingTransfers(ctx context.Context, userID string) (domain.PendingTransfersResponse, error)
+	AcceptTransfer(ctx context.Context, userID string, transferID string) error
+	DeclineTransfer(ctx context.Context, userID string, transferID string) error
+	ExpirePendingTransfers(ctx context.Context) (int, error)
+	ScheduleTransfer(ctx context.Context, senderID string, request domain.ScheduleTransferRequest) (domain.ScheduledTransfer, error)
+	GetScheduledTransfers(ctx context.Context, senderID string) ([]domain.ScheduledTransfer, error)
+	CancelScheduledTransfer(ctx context.Context, senderID string, transferID string) error
+	RunDueScheduledTransfers(ctx context.Context) (int, error)
 }
 
 type merchUsecase struct {
 	merchRepository domain.MerchRepository
+	transfers       config.TransfersConfig
+	logger          *zap.Logger
 }
 
-func NewMerchUsecase(merchRepository domain.MerchRepository) MerchUsecase {
+func NewMerchUsecase(merchRepository domain.MerchRepository, transfers config.TransfersConfig, logger *zap.Logger) MerchUsecase {
 	return &merchUsecase{
 		merchRepository: merchRepository,
+		transfers:       transfers,
+		logger:          logger,
 	}
 }
 
-func (uc *merchUsecase) SendCoins(ctx context.Context, senderID string, receiverUsername string, amount int) error {
+func (uc *merchUsecase) SendCoins(ctx context.Context, senderID string, receiverUsername string, amount int) (domain.Transaction, error) {
 	const maxLen = 255
-	requestID := middleware.GetRequestID(ctx)
+	log := logger.FromContext(ctx, uc.logger)
 	validCharPattern := regexp.MustCompile(`^[a-zA-Zа-яА-ЯёЁ0-9\s\-_]*$`)
 	if !validCharPattern.MatchString(senderID) {
-		logger.AccessLogger.Warn("Input contains invalid characters", zap.String("request_id", requestID))
-		return errors.New("Input contains invalid characters")
+		log.Warn("Input contains invalid characters")
+		return domain.Transaction{}, errors.New("Input contains invalid characters")
 	}
 
 	if len(senderID) > maxLen {
-		logger.AccessLogger.Warn("Input exceeds character limit", zap.String("request_id", requestID))
-		return errors.New("Input exceeds character limit")
+		log.Warn("Input exceeds character limit")
+		return domain.Transaction{}, errors.New("Input exceeds character limit")
 	}
 
 	if amount <= 0 {
-		logger.AccessLogger.Warn("coins needs to be positive", zap.String("request_id", requestID))
-		return errors.New("amount must be greater than 0")
+		log.Warn("coins needs to be positive")
+		return domain.Transaction{}, errors.New("amount must be greater than 0")
 	}
 
-	err := uc.merchRepository.SendCoins(ctx, senderID, receiverUsername, amount)
+	transaction, err := uc.merchRepository.SendCoins(ctx, senderID, receiverUsername, amount)
 	if err != nil {
-		return err
+		return domain.Transaction{}, err
 	}
 
-	return nil
+	metrics.CoinsTransferred.WithLabelValues(metrics.TransferDirect).Add(float64(amount))
+	return transaction, nil
 }
 
-func (uc *merchUsecase) GetUserMerchInformation(ctx context.Context, userID string) (domain.UserInformationResponse, error) {
+// GetUserMerchInformation принимает версию, прочитанную вызывающим (например, для ETag), чтобы кэш
+// не отдал данные старше неё без повторного запроса версии
+func (uc *merchUsecase) GetUserMerchInformation(ctx context.Context, userID string, minVersion int64) (domain.UserInformationResponse, error) {
 	const maxLen = 255
-	requestID := middleware.GetRequestID(ctx)
+	log := logger.FromContext(ctx, uc.logger)
 	validCharPattern := regexp.MustCompile(`^[a-zA-Zа-яА-ЯёЁ0-9\s\-_]*$`)
 	if !validCharPattern.MatchString(userID) {
-		logger.AccessLogger.Warn("Input contains invalid characters", zap.String("request_id", requestID))
+		log.Warn("Input contains invalid characters")
 		return domain.UserInformationResponse{}, errors.New("Input contains invalid characters")
 	}
 
 	if len(userID) > maxLen {
-		logger.AccessLogger.Warn("Input exceeds character limit", zap.String("request_id", requestID))
+		log.Warn("Input exceeds character limit")
 		return domain.UserInformationResponse{}, errors.New("Input exceeds character limit")
 	}
 
-	response, err := uc.merchRepository.GetUserMerchInformation(ctx, userID)
+	response, err := uc.merchRepository.GetUserMerchInformation(ctx, userID, minVersion)
 	if err != nil {
 		return domain.UserInformationResponse{}, err
 	}
 	return response, nil
 }
 
-func (uc *merchUsecase) BuyItem(ctx context.Context, userID string, itemName string) error {
+// GetUserInfoVersion возвращает версию данных GetUserMerchInformation. Она растёт при каждом изменении баланса,
+// инвентаря или истории переводов пользователя
+func (uc *merchUsecase) GetUserInfoVersion(ctx context.Context, userID string) (int64, error) {
+	if err := uc.validateUserID(ctx, userID); err != nil {
+		return 0, err
+	}
+	return uc.merchRepository.GetUserInfoVersion(ctx, userID)
+}
+
+func (uc *merchUsecase) BuyItem(ctx context.Context, userID string, itemName string, promoCode string) (domain.Purchase, error) {
 	const maxLen = 255
-	requestID := middleware.GetRequestID(ctx)
+	log := logger.FromContext(ctx, uc.logger)
 	validCharPattern := regexp.MustCompile(`^[a-zA-Zа-яА-ЯёЁ0-9\s\-_]*$`)
 	if !validCharPattern.MatchString(userID) {
-		logger.AccessLogger.Warn("Input contains invalid characters", zap.String("request_id", requestID))
-		return errors.New("Input contains invalid characters")
+		log.Warn("Input contains invalid characters")
+		return domain.Purchase{}, errors.New("Input contains invalid characters")
 	}
 
 	if len(userID) > maxLen {
-		logger.AccessLogger.Warn("Input exceeds character limit", zap.String("request_id", requestID))
-		return errors.New("Input exceeds character limit")
+		log.Warn("Input exceeds character limit")
+		return domain.Purchase{}, errors.New("Input exceeds character limit")
 	}
 
-	itemCost, exists := domain.MerchTypes[itemName]
+	basePrice, exists := domain.MerchTypes[itemName]
 	if !exists {
-		logger.AccessLogger.Warn("Item not found", zap.String("request_id", requestID), zap.String("itemName", itemName))
-		return errors.New("item not found in merch types")
+		log.Warn("Item not found", zap.String("itemName", itemName))
+		return domain.Purchase{}, errors.New("item not found in merch types")
+	}
+
+	promoCode = strings.ToUpper(strings.TrimSpace(promoCode))
+	if len(promoCode) > maxPromoCodeLen || !validCharPattern.MatchString(promoCode) {
+		log.Warn("Invalid promo code")
+		return domain.Purchase{}, errors.New("invalid promo code")
+	}
+
+	sale, err := uc.merchRepository.GetActivePriceSchedule(ctx, itemName, time.Now())
+	if err != nil {
+		return domain.Purchase{}, err
+	}
+	price := basePrice
+	if sale != nil {
+		price = sale.Price
+	}
+
+	purchase, err := uc.merchRepository.BuyItem(ctx, userID, itemName, basePrice, price, promoCode)
+	if err != nil {
+		reason := metrics.PurchaseFailedOther
+		if err.Error() == "not enough coins" {
+			reason = metrics.PurchaseFailedInsufficientFunds
+		}
+		metrics.PurchasesFailed.WithLabelValues(reason).Inc()
+		return domain.Purchase{}, err
+	}
+
+	metrics.ItemsBought.WithLabelValues(itemName).Inc()
+	return purchase, nil
+}
+
+// GetCatalog возвращает товары с ценами с учётом действующих распродаж
+func (uc *merchUsecase) GetCatalog(ctx context.Context) ([]domain.CatalogItem, error) {
+	schedules, err := uc.merchRepository.GetActivePriceSchedules(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	sales := make(map[string]domain.PriceSchedule, len(schedules))
+	for _, schedule := range schedules {
+		if current, ok := sales[schedule.ItemName]; !ok || schedule.Price < current.Price {
+			sales[schedule.ItemName] = schedule
+		}
+	}
+
+	catalog := make([]domain.CatalogItem, 0, len(domain.MerchTypes))
+	for name, price := range domain.MerchTypes {
+		item := domain.CatalogItem{
+			Name:      name,
+			Price:     price,
+			BasePrice: price,
+		}
+		if sale, ok := sales[name]; ok {
+			endsAt := sale.EndsAt
+			item.Price = sale.Price
+			item.SaleEndsAt = &endsAt
+		}
+		catalog = append(catalog, item)
+	}
+	sort.Slice(catalog, func(i, j int) bool {
+		return catalog[i].Name < catalog[j].Name
+	})
+	return catalog, nil
+}
+
+func (uc *merchUsecase) GetLeaderboard(ctx context.Context, category string, window string, limit int) (domain.LeaderboardResponse, error) {
+	log := logger.FromContext(ctx, uc.logger)
+	if category == "" {
+		category = domain.LeaderboardReceived
+	}
+	if window == "" {
+		window = domain.LeaderboardWeek
+	}
+	if limit == 0 {
+		limit = defaultLeaderboardLimit
+	}
+
+	if category != domain.LeaderboardReceived && category != domain.LeaderboardThanked {
+		log.Warn("Invalid leaderboard category", zap.String("category", category))
+		return domain.LeaderboardResponse{}, errors.New("invalid leaderboard category")
+	}
+	if limit < 0 || limit > maxLeaderboardLimit {
+		log.Warn("Invalid leaderboard limit", zap.Int("limit", limit))
+		return domain.LeaderboardResponse{}, errors.New("invalid limit")
+	}
+
+	var since *time.Time
+	switch window {
+	case domain.LeaderboardWeek:
+		weekAgo := time.Now().AddDate(0, 0, -7)
+		since = &weekAgo
+	case domain.LeaderboardMonth:
+		monthAgo := time.Now().AddDate(0, -1, 0)
+		since = &monthAgo
+	case domain.LeaderboardAllTime:
+	default:
+		log.Warn("Invalid leaderboard window", zap.String("window", window))
+		return domain.LeaderboardResponse{}, errors.New("invalid leaderboard window")
+	}
+
+	entries, err := uc.merchRepository.GetLeaderboard(ctx, category, since, limit)
+	if err != nil {
+		return domain.LeaderboardResponse{}, err
+	}
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+
+	return domain.LeaderboardResponse{
+		Category: category,
+		Window:   window,
+		Entries:  entries,
+	}, nil
+}
+
+func (uc *merchUsecase) SendCoinsPending(ctx context.Context, senderID string, receiverUsername string, amount int) (string, error) {
+	log := logger.FromContext(ctx, uc.logger)
+	if err := uc.validateUserID(ctx, senderID); err != nil {
+		return "", err
+	}
+
+	if amount <= 0 {
+		log.Warn("coins needs to be positive")
+		return "", errors.New("amount must be greater than 0")
+	}
+
+	transferID, err := uc.merchRepository.CreatePendingTransfer(ctx, senderID, receiverUsername, amount, time.Now().Add(uc.transfers.PendingTTL))
+	if err != nil {
+		return "", err
+	}
+	return transferID, nil
+}
+
+func (uc *merchUsecase) GetPendingTransfers(ctx context.Context, userID string) (domain.PendingTransfersResponse, error) {
+	if err := uc.validateUserID(ctx, userID); err != nil {
+		return domain.PendingTransfersResponse{}, err
 	}
+	return uc.merchRepository.GetPendingTransfers(ctx, userID)
+}
 
-	err := uc.merchRepository.BuyItem(ctx, userID, itemName, itemCost)
+func (uc *merchUsecase) AcceptTransfer(ctx context.Context, userID string, transferID string) error {
+	if err := uc.validateTransferID(ctx, transferID); err != nil {
+		return err
+	}
+	transaction, err := uc.merchRepository.AcceptPendingTransfer(ctx, userID, transferID)
 	if err != nil {
 		return err
 	}
+
+	metrics.CoinsTransferred.WithLabelValues(metrics.TransferPending).Add(float64(transaction.Amount))
+	return nil
+}
+
+func (uc *merchUsecase) DeclineTransfer(ctx context.Context, userID string, transferID string) error {
+	if err := uc.validateTransferID(ctx, transferID); err != nil {
+		return err
+	}
+	_, err := uc.merchRepository.DeclinePendingTransfer(ctx, userID, transferID)
+	return err
+}
+
+func (uc *merchUsecase) ExpirePendingTransfers(ctx context.Context) (int, error) {
+	expired, err := uc.merchRepository.ExpirePendingTransfers(ctx, time.Now())
+	return len(expired), err
+}
+
+func (uc *merchUsecase) validateTransferID(ctx context.Context, transferID string) error {
+	log := logger.FromContext(ctx, uc.logger)
+	if _, err := uuid.Parse(transferID); err != nil {
+		log.Warn("Invalid transfer id", zap.String("transfer_id", transferID))
+		return errors.New("invalid transfer id")
+	}
+	return nil
+}
+
+func (uc *merchUsecase) ScheduleTransfer(ctx context.Context, senderID string, request domain.ScheduleTransferRequest) (domain.ScheduledTransfer, error) {
+	log := logger.FromContext(ctx, uc.logger)
+	if err := uc.validateUserID(ctx, senderID); err != nil {
+		return domain.ScheduledTransfer{}, err
+	}
+
+	if request.Amount <= 0 {
+		log.Warn("coins needs to be positive")
+		return domain.ScheduledTransfer{}, errors.New("amount must be greater than 0")
+	}
+
+	if len(request.Schedule) > maxScheduleLen {
+		log.Warn("Schedule exceeds character limit")
+		return domain.ScheduledTransfer{}, errors.New("Input exceeds character limit")
+	}
+
+	now := time.Now()
+	transfer := domain.ScheduledTransfer{
+		SenderID:         senderID,
+		ReceiverUsername: request.ToUser,
+		Amount:           request.Amount,
+		Schedule:         request.Schedule,
+		Status:           domain.ScheduleStatusActive,
+	}
+
+	switch {
+	case request.Schedule != "":
+		schedule, err := cron.ParseStandard(request.Schedule)
+		if err != nil {
+			log.Warn("Invalid schedule", zap.String("schedule", request.Schedule), zap.Error(err))
+			return domain.ScheduledTransfer{}, errors.New("invalid schedule")
+		}
+		transfer.NextRunAt = schedule.Next(now)
+		if request.RunAt != nil {
+			transfer.NextRunAt = *request.RunAt
+		}
+	case request.RunAt != nil:
+		transfer.NextRunAt = *request.RunAt
+	default:
+		log.Warn("Missing runAt and schedule")
+		return domain.ScheduledTransfer{}, errors.New("runAt or schedule is required")
+	}
+
+	if !transfer.NextRunAt.After(now) {
+		log.Warn("runAt is in the past")
+		return domain.ScheduledTransfer{}, errors.New("runAt must be in the future")
+	}
+
+	if err := uc.merchRepository.CreateScheduledTransfer(ctx, &transfer); err != nil {
+		return domain.ScheduledTransfer{}, err
+	}
+	return transfer, nil
+}
+
+func (uc *merchUsecase) GetScheduledTransfers(ctx context.Context, senderID string) ([]domain.ScheduledTransfer, error) {
+	if err := uc.validateUserID(ctx, senderID); err != nil {
+		return nil, err
+	}
+	return uc.merchRepository.GetScheduledTransfers(ctx, senderID)
+}
+
+func (uc *merchUsecase) CancelScheduledTransfer(ctx context.Context, senderID string, transferID string) error {
+	if err := uc.validateTransferID(ctx, transferID); err != nil {
+		return err
+	}
+	return uc.merchRepository.CancelScheduledTransfer(ctx, senderID, transferID)
+}
+
+// RunDueScheduledTransfers выполняет наступившие переводы. Перевод и запись итога запуска фиксируются
+// в одной транзакции, поэтому отмена ctx или падение процесса не приводят к повторному переводу:
+// незавершённые запуски подхватятся после истечения аренды
+func (uc *merchUsecase) RunDueScheduledTransfers(ctx context.Context) (int, error) {
+	log := logger.FromContext(ctx, uc.logger)
+	transfers, err := uc.merchRepository.ClaimDueScheduledTransfers(ctx, time.Now(), uc.transfers.ScheduledLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range transfers {
+		if ctx.Err() != nil {
+			return i, ctx.Err()
+		}
+
+		transfer := &transfers[i]
+		result, err := uc.merchRepository.RunScheduledTransfer(ctx, transfer, time.Now())
+		if err != nil {
+			log.Error("Failed to run scheduled transfer", zap.String("transfer_id", transfer.UUID), zap.Error(err))
+			continue
+		}
+		switch result.Outcome {
+		case domain.ScheduledRunDone:
+			metrics.CoinsTransferred.WithLabelValues(metrics.TransferScheduled).Add(float64(transfer.Amount))
+		case domain.ScheduledRunFailed:
+			log.Warn("Scheduled transfer failed", zap.String("transfer_id", transfer.UUID), zap.String("error", result.Error))
+		}
+	}
+	return len(transfers), nil
+}
+
+func (uc *merchUsecase) validateUserID(ctx context.Context, userID string) error {
+	const maxLen = 255
+	log := logger.FromContext(ctx, uc.logger)
+	validCharPattern := regexp.MustCompile(`^[a-zA-Zа-яА-ЯёЁ0-9\s\-_]*$`)
+	if !validCharPattern.MatchString(userID) {
+		log.Warn("Input contains invalid characters")
+		return errors.New("Input contains invalid characters")
+	}
+
+	if len(userID) > maxLen {
+		log.Warn("Input exceeds character limit")
+		return errors.New("Input exceeds character limit")
+	}
 	return nil
 }
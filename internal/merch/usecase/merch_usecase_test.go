@@ -2,19 +2,29 @@ package usecase
 
 import (
 	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/config"
 	"avito_staj_2025/internal/merch/mocks"
-	"avito_staj_2025/internal/service/logger"
+	"avito_staj_2025/internal/service/metrics"
+	"avito_staj_2025/internal/service/tracing"
 	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestSendCoins(t *testing.T) {
-	logger.AccessLogger = zap.NewNop()
 	mockRepo := new(mocks.MockMerchRepository)
-	uc := NewMerchUsecase(mockRepo)
+	uc := NewMerchUsecase(mockRepo, config.Default().Transfers, zap.NewNop())
 
 	ctx := context.Background()
 	validSender := "user123"
@@ -25,36 +35,36 @@ func TestSendCoins(t *testing.T) {
 	negativeAmount := -50
 
 	t.Run("Success", func(t *testing.T) {
-		mockRepo.On("SendCoins", ctx, validSender, validReceiver, validAmount).Return(nil)
+		mockRepo.On("SendCoins", ctx, validSender, validReceiver, validAmount).Return(domain.Transaction{UUID: "transaction-uuid"}, nil)
 
-		err := uc.SendCoins(ctx, validSender, validReceiver, validAmount)
+		transaction, err := uc.SendCoins(ctx, validSender, validReceiver, validAmount)
 		assert.NoError(t, err)
+		assert.Equal(t, "transaction-uuid", transaction.UUID)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Invalid Sender ID", func(t *testing.T) {
-		err := uc.SendCoins(ctx, invalidSender, validReceiver, validAmount)
+		_, err := uc.SendCoins(ctx, invalidSender, validReceiver, validAmount)
 		assert.Error(t, err)
 		assert.Equal(t, "Input contains invalid characters", err.Error())
 	})
 
 	t.Run("Sender ID Too Long", func(t *testing.T) {
-		err := uc.SendCoins(ctx, tooLongSender, validReceiver, validAmount)
+		_, err := uc.SendCoins(ctx, tooLongSender, validReceiver, validAmount)
 		assert.Error(t, err)
 		assert.Equal(t, "Input exceeds character limit", err.Error())
 	})
 
 	t.Run("Negative Amount", func(t *testing.T) {
-		err := uc.SendCoins(ctx, validSender, validReceiver, negativeAmount)
+		_, err := uc.SendCoins(ctx, validSender, validReceiver, negativeAmount)
 		assert.Error(t, err)
 		assert.Equal(t, "amount must be greater than 0", err.Error())
 	})
 }
 
 func TestGetUserMerchInformation(t *testing.T) {
-	logger.AccessLogger = zap.NewNop()
 	mockRepo := new(mocks.MockMerchRepository)
-	uc := NewMerchUsecase(mockRepo)
+	uc := NewMerchUsecase(mockRepo, config.Default().Transfers, zap.NewNop())
 
 	ctx := context.Background()
 	validUserID := "user123"
@@ -79,31 +89,30 @@ func TestGetUserMerchInformation(t *testing.T) {
 	}
 
 	t.Run("Success", func(t *testing.T) {
-		mockRepo.On("GetUserMerchInformation", ctx, validUserID).Return(expectedResponse, nil)
+		mockRepo.On("GetUserMerchInformation", ctx, validUserID, int64(0)).Return(expectedResponse, nil)
 
-		response, err := uc.GetUserMerchInformation(ctx, validUserID)
+		response, err := uc.GetUserMerchInformation(ctx, validUserID, 0)
 		assert.NoError(t, err)
 		assert.Equal(t, expectedResponse, response)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Invalid User ID", func(t *testing.T) {
-		_, err := uc.GetUserMerchInformation(ctx, invalidUserID)
+		_, err := uc.GetUserMerchInformation(ctx, invalidUserID, 0)
 		assert.Error(t, err)
 		assert.Equal(t, "Input contains invalid characters", err.Error())
 	})
 
 	t.Run("User ID Too Long", func(t *testing.T) {
-		_, err := uc.GetUserMerchInformation(ctx, tooLongUserID)
+		_, err := uc.GetUserMerchInformation(ctx, tooLongUserID, 0)
 		assert.Error(t, err)
 		assert.Equal(t, "Input exceeds character limit", err.Error())
 	})
 }
 
 func TestBuyItem(t *testing.T) {
-	logger.AccessLogger = zap.NewNop()
 	mockRepo := new(mocks.MockMerchRepository)
-	uc := NewMerchUsecase(mockRepo)
+	uc := NewMerchUsecase(mockRepo, config.Default().Transfers, zap.NewNop())
 
 	ctx := context.Background()
 	validUserID := "user123"
@@ -117,29 +126,368 @@ func TestBuyItem(t *testing.T) {
 		"shield": 150,
 	}
 
+	mockRepo.On("GetActivePriceSchedule", ctx, validItem, mock.AnythingOfType("time.Time")).Return(nil, nil)
+
 	t.Run("Success", func(t *testing.T) {
-		mockRepo.On("BuyItem", ctx, validUserID, validItem, 100).Return(nil)
+		mockRepo.On("BuyItem", ctx, validUserID, validItem, 100, 100, "").Return(domain.Purchase{UUID: "purchase-uuid"}, nil)
 
-		err := uc.BuyItem(ctx, validUserID, validItem)
+		purchase, err := uc.BuyItem(ctx, validUserID, validItem, "")
 		assert.NoError(t, err)
+		assert.Equal(t, "purchase-uuid", purchase.UUID)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Invalid User ID", func(t *testing.T) {
-		err := uc.BuyItem(ctx, invalidUserID, validItem)
+		_, err := uc.BuyItem(ctx, invalidUserID, validItem, "")
 		assert.Error(t, err)
 		assert.Equal(t, "Input contains invalid characters", err.Error())
 	})
 
 	t.Run("User ID Too Long", func(t *testing.T) {
-		err := uc.BuyItem(ctx, tooLongUserID, validItem)
+		_, err := uc.BuyItem(ctx, tooLongUserID, validItem, "")
 		assert.Error(t, err)
 		assert.Equal(t, "Input exceeds character limit", err.Error())
 	})
 
 	t.Run("Item Not Found", func(t *testing.T) {
-		err := uc.BuyItem(ctx, validUserID, nonExistentItem)
+		_, err := uc.BuyItem(ctx, validUserID, nonExistentItem, "")
 		assert.Error(t, err)
 		assert.Equal(t, "item not found in merch types", err.Error())
 	})
+
+	t.Run("Promo Code Normalized", func(t *testing.T) {
+		mockRepo.On("BuyItem", ctx, validUserID, validItem, 100, 100, "SALE10").Return(domain.Purchase{UUID: "purchase-uuid"}, nil)
+
+		_, err := uc.BuyItem(ctx, validUserID, validItem, " sale10 ")
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Promo Code", func(t *testing.T) {
+		_, err := uc.BuyItem(ctx, validUserID, validItem, "<script>")
+		assert.Error(t, err)
+		assert.Equal(t, "invalid promo code", err.Error())
+	})
+}
+
+func TestBusinessMetrics(t *testing.T) {
+	mockRepo := new(mocks.MockMerchRepository)
+	uc := NewMerchUsecase(mockRepo, config.Default().Transfers, zap.NewNop())
+	ctx := context.Background()
+
+	domain.MerchTypes = map[string]int{
+		"sword":  100,
+		"shield": 150,
+	}
+	mockRepo.On("GetActivePriceSchedule", ctx, mock.Anything, mock.AnythingOfType("time.Time")).Return(nil, nil)
+
+	t.Run("Coins Transferred", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.CoinsTransferred.WithLabelValues(metrics.TransferDirect))
+		mockRepo.On("SendCoins", ctx, "user123", "receiver456", 40).Return(domain.Transaction{UUID: "transaction-uuid"}, nil)
+
+		_, err := uc.SendCoins(ctx, "user123", "receiver456", 40)
+		assert.NoError(t, err)
+		assert.Equal(t, before+40, testutil.ToFloat64(metrics.CoinsTransferred.WithLabelValues(metrics.TransferDirect)))
+	})
+
+	t.Run("Item Bought", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.ItemsBought.WithLabelValues("sword"))
+		mockRepo.On("BuyItem", ctx, "user123", "sword", 100, 100, "").Return(domain.Purchase{UUID: "purchase-uuid"}, nil)
+
+		_, err := uc.BuyItem(ctx, "user123", "sword", "")
+		assert.NoError(t, err)
+		assert.Equal(t, before+1, testutil.ToFloat64(metrics.ItemsBought.WithLabelValues("sword")))
+	})
+
+	t.Run("Purchase Failed - Insufficient Funds", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.PurchasesFailed.WithLabelValues(metrics.PurchaseFailedInsufficientFunds))
+		boughtBefore := testutil.ToFloat64(metrics.ItemsBought.WithLabelValues("shield"))
+		mockRepo.On("BuyItem", ctx, "user123", "shield", 150, 150, "").Return(domain.Purchase{}, errors.New("not enough coins"))
+
+		_, err := uc.BuyItem(ctx, "user123", "shield", "")
+		assert.Error(t, err)
+		assert.Equal(t, before+1, testutil.ToFloat64(metrics.PurchasesFailed.WithLabelValues(metrics.PurchaseFailedInsufficientFunds)))
+		assert.Equal(t, boughtBefore, testutil.ToFloat64(metrics.ItemsBought.WithLabelValues("shield")))
+	})
+}
+
+func TestSendCoinsPending(t *testing.T) {
+	mockRepo := new(mocks.MockMerchRepository)
+	uc := NewMerchUsecase(mockRepo, config.Default().Transfers, zap.NewNop())
+
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo.On("CreatePendingTransfer", ctx, "user123", "receiver456", 100, mock.AnythingOfType("time.Time")).
+			Return("transfer-uuid", nil)
+
+		transferID, err := uc.SendCoinsPending(ctx, "user123", "receiver456", 100)
+		assert.NoError(t, err)
+		assert.Equal(t, "transfer-uuid", transferID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Negative Amount", func(t *testing.T) {
+		_, err := uc.SendCoinsPending(ctx, "user123", "receiver456", -1)
+		assert.Error(t, err)
+		assert.Equal(t, "amount must be greater than 0", err.Error())
+	})
+}
+
+func TestResolveTransfer(t *testing.T) {
+	mockRepo := new(mocks.MockMerchRepository)
+	uc := NewMerchUsecase(mockRepo, config.Default().Transfers, zap.NewNop())
+
+	ctx := context.Background()
+	transferID := "3f2504e0-4f89-11d3-9a0c-0305e82c3301"
+
+	t.Run("Accept", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.CoinsTransferred.WithLabelValues(metrics.TransferPending))
+		mockRepo.On("AcceptPendingTransfer", ctx, "user123", transferID).
+			Return(domain.Transaction{UUID: "transaction-uuid", SenderID: "sender", ReceiverID: "user123", Amount: 25}, nil)
+
+		err := uc.AcceptTransfer(ctx, "user123", transferID)
+		assert.NoError(t, err)
+		assert.Equal(t, before+25, testutil.ToFloat64(metrics.CoinsTransferred.WithLabelValues(metrics.TransferPending)))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Decline", func(t *testing.T) {
+		mockRepo.On("DeclinePendingTransfer", ctx, "user123", transferID).
+			Return(domain.PendingTransfer{UUID: transferID, SenderID: "sender", ReceiverID: "user123"}, nil)
+
+		err := uc.DeclineTransfer(ctx, "user123", transferID)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Transfer ID", func(t *testing.T) {
+		err := uc.AcceptTransfer(ctx, "user123", "not-a-uuid")
+		assert.Error(t, err)
+		assert.Equal(t, "invalid transfer id", err.Error())
+	})
+}
+
+func TestScheduleTransfer(t *testing.T) {
+	mockRepo := new(mocks.MockMerchRepository)
+	uc := NewMerchUsecase(mockRepo, config.Default().Transfers, zap.NewNop())
+
+	ctx := context.Background()
+
+	t.Run("Success - Recurring", func(t *testing.T) {
+		mockRepo.On("CreateScheduledTransfer", ctx, mock.AnythingOfType("*domain.ScheduledTransfer")).Return(nil).Once()
+
+		transfer, err := uc.ScheduleTransfer(ctx, "user123", domain.ScheduleTransferRequest{ToUser: "receiver456", Amount: 10, Schedule: "0 9 1 * *"})
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ScheduleStatusActive, transfer.Status)
+		assert.Equal(t, 1, transfer.NextRunAt.Day())
+		assert.Equal(t, 9, transfer.NextRunAt.Hour())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Schedule", func(t *testing.T) {
+		_, err := uc.ScheduleTransfer(ctx, "user123", domain.ScheduleTransferRequest{ToUser: "receiver456", Amount: 10, Schedule: "every day"})
+		assert.Error(t, err)
+		assert.Equal(t, "invalid schedule", err.Error())
+	})
+
+	t.Run("RunAt In The Past", func(t *testing.T) {
+		runAt := time.Now().Add(-time.Hour)
+		_, err := uc.ScheduleTransfer(ctx, "user123", domain.ScheduleTransferRequest{ToUser: "receiver456", Amount: 10, RunAt: &runAt})
+		assert.Error(t, err)
+		assert.Equal(t, "runAt must be in the future", err.Error())
+	})
+
+	t.Run("Missing RunAt And Schedule", func(t *testing.T) {
+		_, err := uc.ScheduleTransfer(ctx, "user123", domain.ScheduleTransferRequest{ToUser: "receiver456", Amount: 10})
+		assert.Error(t, err)
+		assert.Equal(t, "runAt or schedule is required", err.Error())
+	})
+
+	t.Run("Schedule Too Long", func(t *testing.T) {
+		_, err := uc.ScheduleTransfer(ctx, "user123", domain.ScheduleTransferRequest{ToUser: "receiver456", Amount: 10, Schedule: strings.Repeat("*", 101)})
+		assert.Error(t, err)
+		assert.Equal(t, "Input exceeds character limit", err.Error())
+	})
+}
+
+func TestRunDueScheduledTransfers(t *testing.T) {
+	mockRepo := new(mocks.MockMerchRepository)
+	uc := NewMerchUsecase(mockRepo, config.Default().Transfers, zap.NewNop())
+
+	ctx := context.Background()
+	oneOff := domain.ScheduledTransfer{UUID: "one-off", SenderID: "user123", ReceiverUsername: "receiver456", Amount: 10, Status: domain.ScheduleStatusActive}
+	recurring := domain.ScheduledTransfer{UUID: "recurring", SenderID: "user123", ReceiverUsername: "receiver789", Amount: 20, Schedule: "@daily", Status: domain.ScheduleStatusActive}
+
+	mockRepo.On("ClaimDueScheduledTransfers", ctx, mock.AnythingOfType("time.Time"), config.Default().Transfers.ScheduledLease).
+		Return([]domain.ScheduledTransfer{oneOff, recurring}, nil)
+	mockRepo.On("RunScheduledTransfer", ctx, mock.MatchedBy(func(transfer *domain.ScheduledTransfer) bool {
+		return transfer.UUID == "one-off"
+	}), mock.AnythingOfType("time.Time")).
+		Return(domain.ScheduledRunResult{Outcome: domain.ScheduledRunDone, Transaction: domain.Transaction{UUID: "transaction-uuid"}}, nil)
+	mockRepo.On("RunScheduledTransfer", ctx, mock.MatchedBy(func(transfer *domain.ScheduledTransfer) bool {
+		return transfer.UUID == "recurring"
+	}), mock.AnythingOfType("time.Time")).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(*domain.ScheduledTransfer).RecordRun(args.Get(2).(time.Time), errors.New("not enough coins"))
+		}).
+		Return(domain.ScheduledRunResult{Outcome: domain.ScheduledRunFailed, Error: "not enough coins"}, nil)
+
+	count, err := uc.RunDueScheduledTransfers(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	mockRepo.AssertExpectations(t)
+
+	t.Run("Skipped Run Not Logged As Failure", func(t *testing.T) {
+		skippedRepo := new(mocks.MockMerchRepository)
+		core, logs := observer.New(zap.WarnLevel)
+		uc := NewMerchUsecase(skippedRepo, config.Default().Transfers, zap.New(core))
+		// Ошибка предыдущего запуска в захваченной строке
+		previouslyFailed := recurring
+		previouslyFailed.LastError = "not enough coins"
+
+		skippedRepo.On("ClaimDueScheduledTransfers", ctx, mock.AnythingOfType("time.Time"), config.Default().Transfers.ScheduledLease).
+			Return([]domain.ScheduledTransfer{previouslyFailed}, nil)
+		skippedRepo.On("RunScheduledTransfer", ctx, mock.Anything, mock.AnythingOfType("time.Time")).
+			Return(domain.ScheduledRunResult{Outcome: domain.ScheduledRunSkipped}, nil)
+
+		count, err := uc.RunDueScheduledTransfers(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.Zero(t, logs.FilterMessage("Scheduled transfer failed").Len())
+	})
+
+	t.Run("Cancelled Before Run", func(t *testing.T) {
+		cancelledRepo := new(mocks.MockMerchRepository)
+		uc := NewMerchUsecase(cancelledRepo, config.Default().Transfers, zap.NewNop())
+		cancelledCtx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		cancelledRepo.On("ClaimDueScheduledTransfers", cancelledCtx, mock.AnythingOfType("time.Time"), config.Default().Transfers.ScheduledLease).
+			Return([]domain.ScheduledTransfer{oneOff}, nil)
+
+		count, err := uc.RunDueScheduledTransfers(cancelledCtx)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 0, count)
+		cancelledRepo.AssertNotCalled(t, "RunScheduledTransfer", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestBuyItemOnSale(t *testing.T) {
+	mockRepo := new(mocks.MockMerchRepository)
+	uc := NewMerchUsecase(mockRepo, config.Default().Transfers, zap.NewNop())
+
+	ctx := context.Background()
+	domain.MerchTypes = map[string]int{
+		"hoody": 300,
+	}
+
+	t.Run("Sale Price Charged", func(t *testing.T) {
+		sale := &domain.PriceSchedule{ItemName: "hoody", Price: 200, EndsAt: time.Now().Add(time.Hour)}
+		mockRepo.On("GetActivePriceSchedule", ctx, "hoody", mock.AnythingOfType("time.Time")).Return(sale, nil).Once()
+		mockRepo.On("BuyItem", ctx, "user123", "hoody", 300, 200, "").Return(domain.Purchase{UUID: "purchase-uuid"}, nil).Once()
+
+		_, err := uc.BuyItem(ctx, "user123", "hoody", "")
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Price Schedule Fetch Error", func(t *testing.T) {
+		mockRepo.On("GetActivePriceSchedule", ctx, "hoody", mock.AnythingOfType("time.Time")).
+			Return(nil, errors.New("failed to fetch price schedule")).Once()
+
+		_, err := uc.BuyItem(ctx, "user123", "hoody", "")
+		assert.Error(t, err)
+		assert.Equal(t, "failed to fetch price schedule", err.Error())
+	})
+}
+
+func TestGetCatalog(t *testing.T) {
+	mockRepo := new(mocks.MockMerchRepository)
+	uc := NewMerchUsecase(mockRepo, config.Default().Transfers, zap.NewNop())
+
+	ctx := context.Background()
+	domain.MerchTypes = map[string]int{
+		"pen":        10,
+		"hoody":      300,
+		"pink-hoody": 500,
+	}
+	endsAt := time.Now().Add(48 * time.Hour)
+	mockRepo.On("GetActivePriceSchedules", ctx, mock.AnythingOfType("time.Time")).Return([]domain.PriceSchedule{
+		{ItemName: "hoody", Price: 250, EndsAt: endsAt.Add(time.Hour)},
+		{ItemName: "hoody", Price: 200, EndsAt: endsAt},
+		{ItemName: "umbrella", Price: 1, EndsAt: endsAt},
+	}, nil)
+
+	catalog, err := uc.GetCatalog(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.CatalogItem{
+		{Name: "hoody", Price: 200, BasePrice: 300, SaleEndsAt: &endsAt},
+		{Name: "pen", Price: 10, BasePrice: 10},
+		{Name: "pink-hoody", Price: 500, BasePrice: 500},
+	}, catalog)
+}
+
+func TestGetLeaderboard(t *testing.T) {
+	mockRepo := new(mocks.MockMerchRepository)
+	uc := NewMerchUsecase(mockRepo, config.Default().Transfers, zap.NewNop())
+
+	ctx := context.Background()
+
+	t.Run("Defaults To Weekly Receivers", func(t *testing.T) {
+		mockRepo.On("GetLeaderboard", ctx, domain.LeaderboardReceived, mock.MatchedBy(func(since *time.Time) bool {
+			return since != nil && time.Since(*since) > 6*24*time.Hour
+		}), 10).Return([]domain.LeaderboardEntry{{Username: "Alice", Score: 300}, {Username: "Bob", Score: 100}}, nil).Once()
+
+		response, err := uc.GetLeaderboard(ctx, "", "", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.LeaderboardWeek, response.Window)
+		assert.Equal(t, 1, response.Entries[0].Rank)
+		assert.Equal(t, 2, response.Entries[1].Rank)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("All Time Has No Lower Bound", func(t *testing.T) {
+		mockRepo.On("GetLeaderboard", ctx, domain.LeaderboardThanked, (*time.Time)(nil), 3).
+			Return([]domain.LeaderboardEntry{}, nil).Once()
+
+		_, err := uc.GetLeaderboard(ctx, domain.LeaderboardThanked, domain.LeaderboardAllTime, 3)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Window", func(t *testing.T) {
+		_, err := uc.GetLeaderboard(ctx, domain.LeaderboardReceived, "year", 10)
+		assert.Error(t, err)
+		assert.Equal(t, "invalid leaderboard window", err.Error())
+	})
+
+	t.Run("Limit Too Large", func(t *testing.T) {
+		_, err := uc.GetLeaderboard(ctx, domain.LeaderboardReceived, domain.LeaderboardWeek, 1000)
+		assert.Error(t, err)
+		assert.Equal(t, "invalid limit", err.Error())
+	})
+}
+
+func TestWithTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewTracerProvider(sdktrace.NewSimpleSpanProcessor(exporter), config.Default().Tracing)
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	mockRepo := new(mocks.MockMerchRepository)
+	uc := WithTracing(NewMerchUsecase(mockRepo, config.Default().Transfers, zap.NewNop()))
+	ctx := context.Background()
+
+	mockRepo.On("SendCoins", mock.Anything, "user123", "receiver456", 100).Return(domain.Transaction{}, errors.New("not enough coins"))
+
+	_, err := uc.SendCoins(ctx, "user123", "receiver456", 100)
+	assert.Error(t, err)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "MerchUsecase.SendCoins", spans[0].Name)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	mockRepo.AssertExpectations(t)
 }
@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/service/tracing"
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tracedMerchUsecase оборачивает каждый метод MerchUsecase в спан
+type tracedMerchUsecase struct {
+	next MerchUsecase
+}
+
+func WithTracing(next MerchUsecase) MerchUsecase {
+	return &tracedMerchUsecase{next: next}
+}
+
+func (uc *tracedMerchUsecase) SendCoins(ctx context.Context, senderID string, receiverUsername string, amount int) (domain.Transaction, error) {
+	ctx, span := tracing.Start(ctx, "MerchUsecase.SendCoins", attribute.String("user_id", senderID), attribute.Int("amount", amount))
+	transaction, err := uc.next.SendCoins(ctx, senderID, receiverUsername, amount)
+	tracing.End(span, err)
+	return transaction, err
+}
+
+func (uc *tracedMerchUsecase) GetUserMerchInformation(ctx context.Context, userID string, minVersion int64) (domain.UserInformationResponse, error) {
+	ctx, span := tracing.Start(ctx, "MerchUsecase.GetUserMerchInformation", attribute.String("user_id", userID))
+	response, err := uc.next.GetUserMerchInformation(ctx, userID, minVersion)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (uc *tracedMerchUsecase) GetUserInfoVersion(ctx context.Context, userID string) (int64, error) {
+	ctx, span := tracing.Start(ctx, "MerchUsecase.GetUserInfoVersion", attribute.String("user_id", userID))
+	version, err := uc.next.GetUserInfoVersion(ctx, userID)
+	tracing.End(span, err)
+	return version, err
+}
+
+func (uc *tracedMerchUsecase) BuyItem(ctx context.Context, userID string, itemName string, promoCode string) (domain.Purchase, error) {
+	ctx, span := tracing.Start(ctx, "MerchUsecase.BuyItem", attribute.String("user_id", userID), attribute.String("item", itemName))
+	purchase, err := uc.next.BuyItem(ctx, userID, itemName, promoCode)
+	tracing.End(span, err)
+	return purchase, err
+}
+
+func (uc *tracedMerchUsecase) GetCatalog(ctx context.Context) ([]domain.CatalogItem, error) {
+	ctx, span := tracing.Start(ctx, "MerchUsecase.GetCatalog")
+	catalog, err := uc.next.GetCatalog(ctx)
+	tracing.End(span, err)
+	return catalog, err
+}
+
+func (uc *tracedMerchUsecase) GetLeaderboard(ctx context.Context, category string, window string, limit int) (domain.LeaderboardResponse, error) {
+	ctx, span := tracing.Start(ctx, "MerchUsecase.GetLeaderboard", attribute.String("category", category), attribute.String("window", window))
+	response, err := uc.next.GetLeaderboard(ctx, category, window, limit)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (uc *tracedMerchUsecase) SendCoinsPending(ctx context.Context, senderID string, receiverUsername string, amount int) (string, error) {
+	ctx, span := tracing.Start(ctx, "MerchUsecase.SendCoinsPending", attribute.String("user_id", senderID), attribute.Int("amount", amount))
+	transferID, err := uc.next.SendCoinsPending(ctx, senderID, receiverUsername, amount)
+	span.SetAttributes(attribute.String("transfer_id", transferID))
+	tracing.End(span, err)
+	return transferID, err
+}
+
+func (uc *tracedMerchUsecase) GetPendingTransfers(ctx context.Context, userID string) (domain.PendingTransfersResponse, error) {
+	ctx, span := tracing.Start(ctx, "MerchUsecase.GetPendingTransfers", attribute.String("user_id", userID))
+	response, err := uc.next.GetPendingTransfers(ctx, userID)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (uc *tracedMerchUsecase) AcceptTransfer(ctx context.Context, userID string, transferID string) error {
+	ctx, span := tracing.Start(ctx, "MerchUsecase.AcceptTransfer", attribute.String("user_id", userID), attribute.String("transfer_id", transferID))
+	err := uc.next.AcceptTransfer(ctx, userID, transferID)
+	tracing.End(span, err)
+	return err
+}
+
+func (uc *tracedMerchUsecase) DeclineTransfer(ctx context.Context, userID string, transferID string) error {
+	ctx, span := tracing.Start(ctx, "MerchUsecase.DeclineTransfer", attribute.String("user_id", userID), attribute.String("transfer_id", transferID))
+	err := uc.next.DeclineTransfer(ctx, userID, transferID)
+	tracing.End(span, err)
+	return err
+}
+
+func (uc *tracedMerchUsecase) ExpirePendingTransfers(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "MerchUsecase.ExpirePendingTransfers")
+	count, err := uc.next.ExpirePendingTransfers(ctx)
+	span.SetAttributes(attribute.Int("expired", count))
+	tracing.End(span, err)
+	return count, err
+}
+
+func (uc *tracedMerchUsecase) ScheduleTransfer(ctx context.Context, senderID string, request domain.ScheduleTransferRequest) (domain.ScheduledTransfer, error) {
+	ctx, span := tracing.Start(ctx, "MerchUsecase.ScheduleTransfer", attribute.String("user_id", senderID), attribute.Int("amount", request.Amount))
+	transfer, err := uc.next.ScheduleTransfer(ctx, senderID, request)
+	tracing.End(span, err)
+	return transfer, err
+}
+
+func (uc *tracedMerchUsecase) GetScheduledTransfers(ctx context.Context, senderID string) ([]domain.ScheduledTransfer, error) {
+	ctx, span := tracing.Start(ctx, "MerchUsecase.GetScheduledTransfers", attribute.String("user_id", senderID))
+	transfers, err := uc.next.GetScheduledTransfers(ctx, senderID)
+	tracing.End(span, err)
+	return transfers, err
+}
+
+func (uc *tracedMerchUsecase) CancelScheduledTransfer(ctx context.Context, senderID string, transferID string) error {
+	ctx, span := tracing.Start(ctx, "MerchUsecase.CancelScheduledTransfer", attribute.String("user_id", senderID), attribute.String("transfer_id", transferID))
+	err := uc.next.CancelScheduledTransfer(ctx, senderID, transferID)
+	tracing.End(span, err)
+	return err
+}
+
+func (uc *tracedMerchUsecase) RunDueScheduledTransfers(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "MerchUsecase.RunDueScheduledTransfers")
+	count, err := uc.next.RunDueScheduledTransfers(ctx)
+	span.SetAttributes(attribute.Int("claimed", count))
+	tracing.End(span, err)
+	return count, err
+}
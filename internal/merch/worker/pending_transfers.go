@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"avito_staj_2025/internal/merch/usecase"
+	"avito_staj_2025/internal/service/logger"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+// PendingTransferWorker периодически возвращает отправителям монеты по непринятым переводам
+type PendingTransferWorker struct {
+	usecase  usecase.MerchUsecase
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+func NewPendingTransferWorker(usecase usecase.MerchUsecase, interval time.Duration, logger *zap.Logger) *PendingTransferWorker {
+	return &PendingTransferWorker{
+		usecase:  usecase,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run блокируется до отмены контекста
+func (w *PendingTransferWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.expire(ctx)
+		}
+	}
+}
+
+func (w *PendingTransferWorker) expire(ctx context.Context) {
+	log := logger.FromContext(ctx, w.logger)
+	count, err := w.usecase.ExpirePendingTransfers(ctx)
+	if err != nil {
+		log.Error("Failed to expire pending transfers", zap.Error(err))
+		return
+	}
+	if count > 0 {
+		log.Info("Expired pending transfers returned to senders", zap.Int("count", count))
+	}
+}
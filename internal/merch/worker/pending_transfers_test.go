@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"avito_staj_2025/internal/merch/mocks"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestPendingTransferWorker(t *testing.T) {
+	t.Run("Expires Transfers On Tick", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		called := make(chan struct{}, 1)
+		mockUsecase.On("ExpirePendingTransfers", mock.Anything).Return(2, nil).Run(func(mock.Arguments) {
+			select {
+			case called <- struct{}{}:
+			default:
+			}
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go NewPendingTransferWorker(mockUsecase, 10*time.Millisecond, zap.NewNop()).Run(ctx)
+
+		select {
+		case <-called:
+		case <-time.After(time.Second):
+			t.Fatal("worker did not expire transfers")
+		}
+	})
+
+	t.Run("Stops On Context Cancel", func(t *testing.T) {
+		mockUsecase := new(mocks.MockMerchUsecase)
+		mockUsecase.On("ExpirePendingTransfers", mock.Anything).Return(0, errors.New("failed to fetch expired transfers"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			NewPendingTransferWorker(mockUsecase, time.Millisecond, zap.NewNop()).Run(ctx)
+			close(done)
+		}()
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("worker did not stop")
+		}
+	})
+}
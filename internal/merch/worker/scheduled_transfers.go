@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"avito_staj_2025/internal/merch/usecase"
+	"avito_staj_2025/internal/service/logger"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+// ScheduledTransferWorker выполняет наступившие запланированные переводы.
+// Может работать одновременно в нескольких экземплярах приложения: переводы захватываются в БД.
+type ScheduledTransferWorker struct {
+	usecase  usecase.MerchUsecase
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+func NewScheduledTransferWorker(usecase usecase.MerchUsecase, interval time.Duration, logger *zap.Logger) *ScheduledTransferWorker {
+	return &ScheduledTransferWorker{
+		usecase:  usecase,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run блокируется до отмены контекста
+func (w *ScheduledTransferWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.run(ctx)
+		}
+	}
+}
+
+func (w *ScheduledTransferWorker) run(ctx context.Context) {
+	log := logger.FromContext(ctx, w.logger)
+	count, err := w.usecase.RunDueScheduledTransfers(ctx)
+	if err != nil {
+		log.Error("Failed to run scheduled transfers", zap.Error(err))
+		return
+	}
+	if count > 0 {
+		log.Info("Scheduled transfers executed", zap.Int("count", count))
+	}
+}
@@ -0,0 +1,31 @@
+package worker
+
+import (
+	"avito_staj_2025/internal/merch/mocks"
+	"context"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestScheduledTransferWorker(t *testing.T) {
+	mockUsecase := new(mocks.MockMerchUsecase)
+	called := make(chan struct{}, 1)
+	mockUsecase.On("RunDueScheduledTransfers", mock.Anything).Return(1, nil).Run(func(mock.Arguments) {
+		select {
+		case called <- struct{}{}:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewScheduledTransferWorker(mockUsecase, 10*time.Millisecond, zap.NewNop()).Run(ctx)
+
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not run scheduled transfers")
+	}
+}
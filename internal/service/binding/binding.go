@@ -0,0 +1,120 @@
+package binding
+
+import (
+	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/service/validation"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// MaxBodyBytes - предел тела запроса. Самые большие запросы API (перевод по расписанию) укладываются в сотни байт
+const MaxBodyBytes = 64 << 10
+
+// Error - тело запроса не прошло разбор или проверку. Status - код ответа клиенту, Fields - ошибки по полям
+type Error struct {
+	Status  int
+	Message string
+	Fields  []domain.FieldError
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// JSON читает из тела ровно один JSON-объект в dst и проверяет теги validate.
+// Тело ограничено MaxBodyBytes, неизвестные поля и данные после объекта отклоняются
+func JSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	if err := checkContentType(r); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return decodeError(err)
+		}
+		return badRequest("request body must contain a single JSON object")
+	}
+
+	if fields := validation.Struct(dst); len(fields) > 0 {
+		return &Error{Status: http.StatusBadRequest, Message: "validation failed", Fields: fields}
+	}
+	return nil
+}
+
+func checkContentType(r *http.Request) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return &Error{Status: http.StatusUnsupportedMediaType, Message: "Content-Type must be application/json"}
+	}
+	return nil
+}
+
+func decodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return badRequest("request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return badRequest("request body contains malformed JSON")
+	case errors.As(err, &syntaxErr):
+		return badRequest(fmt.Sprintf("request body contains malformed JSON at position %d", syntaxErr.Offset))
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return badRequest("request body must be a JSON object")
+		}
+		return &Error{
+			Status:  http.StatusBadRequest,
+			Message: "validation failed",
+			Fields:  []domain.FieldError{{Field: typeErr.Field, Message: "must be " + jsonType(typeErr.Type)}},
+		}
+	case errors.As(err, &maxBytesErr):
+		return &Error{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("request body must not exceed %d bytes", maxBytesErr.Limit)}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &Error{
+			Status:  http.StatusBadRequest,
+			Message: "validation failed",
+			Fields:  []domain.FieldError{{Field: field, Message: "unknown field"}},
+		}
+	default:
+		return badRequest("request body is invalid: " + err.Error())
+	}
+}
+
+// jsonType переводит тип Go в тип JSON для сообщения клиенту
+func jsonType(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Time{}) {
+		return "an RFC 3339 timestamp"
+	}
+	kind := t.Kind().String()
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "a number"
+	case kind == "bool":
+		return "a boolean"
+	case kind == "string":
+		return "a string"
+	default:
+		return "an object"
+	}
+}
+
+func badRequest(message string) *Error {
+	return &Error{Status: http.StatusBadRequest, Message: message}
+}
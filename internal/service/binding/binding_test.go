@@ -0,0 +1,93 @@
+package binding
+
+import (
+	"avito_staj_2025/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func bind(t *testing.T, contentType string, body string, dst interface{}) *Error {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/api/sendCoin", strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	err := JSON(httptest.NewRecorder(), r, dst)
+	if err == nil {
+		return nil
+	}
+	bindErr, ok := err.(*Error)
+	require.True(t, ok, "unexpected error type %T", err)
+	return bindErr
+}
+
+func TestJSON(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		var data domain.SentRequest
+		err := bind(t, "application/json; charset=utf-8", `{"toUser":"bob","amount":10,"pending":true}`, &data)
+		require.Nil(t, err)
+		assert.Equal(t, domain.SentRequest{ToUser: "bob", Amount: 10, Pending: true}, data)
+	})
+
+	t.Run("Field Validation", func(t *testing.T) {
+		var data domain.SentRequest
+		err := bind(t, "application/json", `{"toUser":"","amount":0}`, &data)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.Status)
+		assert.Equal(t, []domain.FieldError{
+			{Field: "toUser", Message: "is required"},
+			{Field: "amount", Message: "must be at least 1"},
+		}, err.Fields)
+
+		var creds domain.LoginRequest
+		err = bind(t, "application/json", `{"username":"`+strings.Repeat("a", 101)+`","password":"secret123"}`, &creds)
+		require.NotNil(t, err)
+		assert.Equal(t, []domain.FieldError{{Field: "username", Message: "must be at most 100 characters"}}, err.Fields)
+	})
+
+	t.Run("Wrong Field Type", func(t *testing.T) {
+		var data domain.SentRequest
+		err := bind(t, "application/json", `{"toUser":"bob","amount":"ten"}`, &data)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.Status)
+		assert.Equal(t, []domain.FieldError{{Field: "amount", Message: "must be a number"}}, err.Fields)
+	})
+
+	t.Run("Unknown Field", func(t *testing.T) {
+		var data domain.SentRequest
+		err := bind(t, "application/json", `{"toUser":"bob","amount":1,"admin":true}`, &data)
+		require.NotNil(t, err)
+		assert.Equal(t, []domain.FieldError{{Field: "admin", Message: "unknown field"}}, err.Fields)
+	})
+
+	t.Run("Rejected Bodies", func(t *testing.T) {
+		cases := map[string]struct {
+			contentType string
+			body        string
+			status      int
+			message     string
+		}{
+			"missing content type": {"", `{"toUser":"bob","amount":1}`, http.StatusUnsupportedMediaType, "Content-Type must be application/json"},
+			"form content type":    {"application/x-www-form-urlencoded", `toUser=bob`, http.StatusUnsupportedMediaType, "Content-Type must be application/json"},
+			"empty body":           {"application/json", ``, http.StatusBadRequest, "request body is empty"},
+			"malformed":            {"application/json", `{"toUser":`, http.StatusBadRequest, "request body contains malformed JSON"},
+			"syntax error":         {"application/json", `{"toUser" "bob"}`, http.StatusBadRequest, "request body contains malformed JSON at position 11"},
+			"trailing data":        {"application/json", `{"toUser":"bob","amount":1}{"amount":2}`, http.StatusBadRequest, "request body must contain a single JSON object"},
+			"not an object":        {"application/json", `[1,2]`, http.StatusBadRequest, "request body must be a JSON object"},
+			"too large":            {"application/json", `{"toUser":"` + strings.Repeat("a", MaxBodyBytes) + `"}`, http.StatusRequestEntityTooLarge, "request body must not exceed 65536 bytes"},
+		}
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				var data domain.SentRequest
+				err := bind(t, tc.contentType, tc.body, &data)
+				require.NotNil(t, err)
+				assert.Equal(t, tc.status, err.Status)
+				assert.Equal(t, tc.message, err.Message)
+			})
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"avito_staj_2025/internal/config"
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+const redisKeyPrefix = "cache:"
+
+// Cache хранит сериализованные значения со сроком жизни. Реализации: MemoryCache (LRU в памяти процесса)
+// и RedisCache (общий для всех реплик)
+type Cache interface {
+	// Get возвращает ok = false, если ключа нет или срок его жизни истёк
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	// Clear удаляет все ключи этого кэша
+	Clear(ctx context.Context) error
+}
+
+// New выбирает реализацию по конфигурации. Для redis нужен настроенный клиент
+func New(cfg config.CacheConfig, client *redis.Client) (Cache, error) {
+	switch cfg.Backend {
+	case config.CacheBackendRedis:
+		if client == nil {
+			return nil, errors.New("redis cache requires redis.address (REDIS_ADDR)")
+		}
+		return NewRedisCache(client, redisKeyPrefix), nil
+	case config.CacheBackendMemory:
+		return NewMemoryCache(cfg.MaxEntries), nil
+	default:
+		return nil, errors.New("cache is disabled")
+	}
+}
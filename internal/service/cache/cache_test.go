@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return server, client
+}
+
+func TestCache(t *testing.T) {
+	backends := map[string]func(t *testing.T) Cache{
+		"Memory": func(t *testing.T) Cache { return NewMemoryCache(10) },
+		"Redis": func(t *testing.T) Cache {
+			_, client := newTestRedis(t)
+			return NewRedisCache(client, "cache:")
+		},
+	}
+
+	for name, newCache := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			t.Run("Success - Set Then Get", func(t *testing.T) {
+				cache := newCache(t)
+				require.NoError(t, cache.Set(ctx, "user_info:1", []byte("value"), time.Minute))
+
+				value, ok, err := cache.Get(ctx, "user_info:1")
+				require.NoError(t, err)
+				assert.True(t, ok)
+				assert.Equal(t, []byte("value"), value)
+			})
+
+			t.Run("Success - Missing Key", func(t *testing.T) {
+				cache := newCache(t)
+				_, ok, err := cache.Get(ctx, "user_info:1")
+				require.NoError(t, err)
+				assert.False(t, ok)
+			})
+
+			t.Run("Success - Delete And Clear", func(t *testing.T) {
+				cache := newCache(t)
+				for i := 0; i < 3; i++ {
+					require.NoError(t, cache.Set(ctx, "user_info:"+strconv.Itoa(i), []byte("value"), time.Minute))
+				}
+
+				require.NoError(t, cache.Delete(ctx, "user_info:0", "user_info:missing"))
+				_, ok, _ := cache.Get(ctx, "user_info:0")
+				assert.False(t, ok)
+				_, ok, _ = cache.Get(ctx, "user_info:1")
+				assert.True(t, ok)
+
+				require.NoError(t, cache.Clear(ctx))
+				_, ok, _ = cache.Get(ctx, "user_info:2")
+				assert.False(t, ok)
+			})
+		})
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success - Least Recently Used Evicted", func(t *testing.T) {
+		cache := NewMemoryCache(2)
+		require.NoError(t, cache.Set(ctx, "first", []byte("1"), time.Minute))
+		require.NoError(t, cache.Set(ctx, "second", []byte("2"), time.Minute))
+		_, _, _ = cache.Get(ctx, "first")
+		require.NoError(t, cache.Set(ctx, "third", []byte("3"), time.Minute))
+
+		_, ok, _ := cache.Get(ctx, "second")
+		assert.False(t, ok)
+		_, ok, _ = cache.Get(ctx, "first")
+		assert.True(t, ok)
+		assert.Equal(t, 2, cache.Len())
+	})
+
+	t.Run("Success - Expired Entry Removed", func(t *testing.T) {
+		now := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+		cache := NewMemoryCache(2)
+		cache.now = func() time.Time { return now }
+		require.NoError(t, cache.Set(ctx, "key", []byte("value"), time.Minute))
+
+		now = now.Add(time.Minute)
+		_, ok, _ := cache.Get(ctx, "key")
+		assert.False(t, ok)
+		assert.Equal(t, 0, cache.Len())
+	})
+}
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success - Key Has TTL And Prefix", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		cache := NewRedisCache(client, "cache:")
+		require.NoError(t, cache.Set(ctx, "user_info:1", []byte("value"), 30*time.Second))
+
+		assert.Equal(t, 30*time.Second, server.TTL("cache:user_info:1"))
+	})
+
+	t.Run("Success - Clear Keeps Foreign Keys", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		cache := NewRedisCache(client, "cache:")
+		require.NoError(t, server.Set("ratelimit:client", "1"))
+		require.NoError(t, cache.Set(ctx, "user_info:1", []byte("value"), time.Minute))
+
+		require.NoError(t, cache.Clear(ctx))
+		assert.False(t, server.Exists("cache:user_info:1"))
+		assert.True(t, server.Exists("ratelimit:client"))
+	})
+
+	t.Run("Fail - Redis Unavailable", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		cache := NewRedisCache(client, "cache:")
+		server.Close()
+
+		_, _, err := cache.Get(ctx, "user_info:1")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+// Сколько ключей Clear удаляет за одну команду
+const clearBatchSize = 500
+
+// RedisCache делит записи между всеми репликами, поэтому сброс кэша на одной реплике виден остальным.
+// Срок жизни задаётся TTL ключа
+type RedisCache struct {
+	client redis.Cmdable
+	prefix string
+}
+
+func NewRedisCache(client redis.Cmdable, prefix string) *RedisCache {
+	return &RedisCache{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("redis cache: %w", err)
+	}
+	return value, true, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.client.Set(ctx, c.prefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("redis cache: %w", err)
+	}
+	return nil
+}
+
+func (c *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefix + key
+	}
+	if err := c.client.Del(ctx, prefixed...).Err(); err != nil {
+		return fmt.Errorf("redis cache: %w", err)
+	}
+	return nil
+}
+
+// Clear перебирает ключи через SCAN, чтобы не блокировать Redis на больших базах, как KEYS
+func (c *RedisCache) Clear(ctx context.Context) error {
+	iter := c.client.Scan(ctx, 0, c.prefix+"*", clearBatchSize).Iterator()
+	batch := make([]string, 0, clearBatchSize)
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == clearBatchSize {
+			if err := c.client.Del(ctx, batch...).Err(); err != nil {
+				return fmt.Errorf("redis cache: %w", err)
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("redis cache: %w", err)
+	}
+	if len(batch) > 0 {
+		if err := c.client.Del(ctx, batch...).Err(); err != nil {
+			return fmt.Errorf("redis cache: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package dbrouter
+
+import (
+	"gorm.io/gorm"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Router выбирает подключение для запроса. Изменения и чтения, от которых зависят изменения, идут в primary,
+// остальные чтения распределяются по репликам по кругу. Пользователи, чей баланс менялся последние
+// readYourWrites, читают из primary, чтобы отставание реплики не показало им старые данные.
+// Отметки хранятся в памяти процесса: запрос на другой экземпляр сервиса может прочитать реплику
+type Router struct {
+	primary        *gorm.DB
+	replicas       []*gorm.DB
+	readYourWrites time.Duration
+	next           atomic.Uint64
+
+	mu        sync.Mutex
+	written   map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New без реплик возвращает роутер, который всегда отдаёт primary
+func New(primary *gorm.DB, replicas []*gorm.DB, readYourWrites time.Duration) *Router {
+	return &Router{
+		primary:        primary,
+		replicas:       replicas,
+		readYourWrites: readYourWrites,
+		written:        make(map[string]time.Time),
+		lastSweep:      time.Now(),
+		now:            time.Now,
+	}
+}
+
+func (r *Router) Primary() *gorm.DB {
+	return r.primary
+}
+
+// Read возвращает подключение для чтения данных перечисленных пользователей.
+// Без пользователей (например, для лидерборда) всегда выбирается реплика
+func (r *Router) Read(userIDs ...string) *gorm.DB {
+	if len(r.replicas) == 0 || r.recentlyWritten(userIDs) {
+		return r.primary
+	}
+	return r.replicas[(r.next.Add(1)-1)%uint64(len(r.replicas))]
+}
+
+// MarkWritten вызывается после фиксации транзакции, изменившей данные пользователей
+func (r *Router) MarkWritten(userIDs ...string) {
+	if len(r.replicas) == 0 || r.readYourWrites <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	// Очистка раз в окно: устаревшая отметка уже не влияет на выбор подключения
+	if now.Sub(r.lastSweep) >= r.readYourWrites {
+		for userID, writtenAt := range r.written {
+			if now.Sub(writtenAt) >= r.readYourWrites {
+				delete(r.written, userID)
+			}
+		}
+		r.lastSweep = now
+	}
+	for _, userID := range userIDs {
+		if userID != "" {
+			r.written[userID] = now
+		}
+	}
+}
+
+// Len возвращает число хранимых отметок, включая устаревшие до очередной очистки
+func (r *Router) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.written)
+}
+
+func (r *Router) recentlyWritten(userIDs []string) bool {
+	if len(userIDs) == 0 || r.readYourWrites <= 0 {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	for _, userID := range userIDs {
+		if writtenAt, ok := r.written[userID]; ok && now.Sub(writtenAt) < r.readYourWrites {
+			return true
+		}
+	}
+	return false
+}
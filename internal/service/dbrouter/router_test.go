@@ -0,0 +1,65 @@
+package dbrouter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestRouter(t *testing.T) {
+	primary, first, second := &gorm.DB{}, &gorm.DB{}, &gorm.DB{}
+
+	t.Run("Success - Without Replicas Reads From Primary", func(t *testing.T) {
+		router := New(primary, nil, time.Second)
+		router.MarkWritten("user-1")
+
+		assert.Same(t, primary, router.Read())
+		assert.Same(t, primary, router.Read("user-2"))
+		assert.Equal(t, 0, router.Len())
+	})
+
+	t.Run("Success - Replicas Used In Turn", func(t *testing.T) {
+		router := New(primary, []*gorm.DB{first, second}, time.Second)
+
+		assert.Same(t, first, router.Read())
+		assert.Same(t, second, router.Read("user-1"))
+		assert.Same(t, first, router.Read())
+		assert.Same(t, primary, router.Primary())
+	})
+
+	t.Run("Success - Recent Writer Reads From Primary", func(t *testing.T) {
+		now := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+		router := New(primary, []*gorm.DB{first}, 5*time.Second)
+		router.now = func() time.Time { return now }
+		router.MarkWritten("sender", "receiver")
+
+		assert.Same(t, primary, router.Read("sender"))
+		assert.Same(t, primary, router.Read("other", "receiver"))
+		assert.Same(t, first, router.Read("other"))
+		assert.Same(t, first, router.Read())
+
+		now = now.Add(5 * time.Second)
+		assert.Same(t, first, router.Read("sender"))
+	})
+
+	t.Run("Success - Zero Window Disables Stickiness", func(t *testing.T) {
+		router := New(primary, []*gorm.DB{first}, 0)
+		router.MarkWritten("user-1")
+
+		assert.Same(t, first, router.Read("user-1"))
+		assert.Equal(t, 0, router.Len())
+	})
+
+	t.Run("Success - Stale Marks Swept", func(t *testing.T) {
+		now := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+		router := New(primary, []*gorm.DB{first}, time.Second)
+		router.now = func() time.Time { return now }
+		router.lastSweep = now
+		router.MarkWritten("user-1")
+
+		now = now.Add(2 * time.Second)
+		router.MarkWritten("user-2")
+		assert.Equal(t, 1, router.Len())
+	})
+}
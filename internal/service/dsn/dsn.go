@@ -5,20 +5,7 @@ import (
 	"os"
 )
 
-// FromEnv собирает DSN строку из переменных окружения
-func FromEnv() string {
-	host := os.Getenv("DB_HOST")
-	if host == "" {
-		return ""
-	}
-	port := os.Getenv("DB_PORT")
-	user := os.Getenv("DB_USER")
-	pass := os.Getenv("DB_PASS")
-	dbname := os.Getenv("DB_NAME")
-
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", host, port, user, pass, dbname)
-}
-
+// FromEnvE2E собирает DSN тестовой БД для E2E-тестов
 func FromEnvE2E() string {
 	host := os.Getenv("DB_HOST_TEST")
 	if host == "" {
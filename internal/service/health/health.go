@@ -0,0 +1,188 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check - проверка одной зависимости. Ошибка означает, что экземпляр не готов принимать запросы
+type Check struct {
+	Name string
+	Fn   func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Response struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Checker обслуживает /healthz и /readyz
+type Checker struct {
+	checks       []Check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// SetShuttingDown переводит /readyz в состояние отказа на время graceful shutdown
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Liveness отвечает, что процесс жив. Зависимости не проверяются, чтобы падение БД не приводило к перезапуску
+func (c *Checker) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, Response{Status: StatusOK})
+}
+
+// Readiness проверяет все зависимости параллельно
+func (c *Checker) Readiness(w http.ResponseWriter, r *http.Request) {
+	if c.shuttingDown.Load() {
+		writeResponse(w, http.StatusServiceUnavailable, Response{
+			Status: StatusFail,
+			Checks: map[string]CheckResult{"shutdown": {Status: StatusFail, Error: "server is shutting down"}},
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeout)
+	defer cancel()
+
+	response := Response{Status: StatusOK, Checks: c.run(ctx)}
+	code := http.StatusOK
+	for _, result := range response.Checks {
+		if result.Status != StatusOK {
+			response.Status = StatusFail
+			code = http.StatusServiceUnavailable
+			break
+		}
+	}
+	writeResponse(w, code, response)
+}
+
+func (c *Checker) run(ctx context.Context) map[string]CheckResult {
+	results := make(map[string]CheckResult, len(c.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			start := time.Now()
+			err := check.Fn(ctx)
+			result := CheckResult{
+				Status:    StatusOK,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+			mu.Lock()
+			results[check.Name] = result
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+	return results
+}
+
+func writeResponse(w http.ResponseWriter, code int, response Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// PostgresCheck проверяет доступность пула соединений
+func PostgresCheck(db *gorm.DB) Check {
+	return namedPostgresCheck("postgres", db)
+}
+
+// PostgresReplicaCheck проверяет реплику для чтения: без неё часть запросов /api/info и лидерборда падала бы
+func PostgresReplicaCheck(index int, db *gorm.DB) Check {
+	return namedPostgresCheck(fmt.Sprintf("postgres_replica_%d", index), db)
+}
+
+func namedPostgresCheck(name string, db *gorm.DB) Check {
+	return Check{
+		Name: name,
+		Fn: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		},
+	}
+}
+
+// MigrationsCheck проверяет, что мигратор уже создал таблицы и колонки всех моделей. Колонки таблицы
+// читаются одним запросом, чтобы проверка оставалась дешёвой при частых запросах /readyz
+func MigrationsCheck(db *gorm.DB, models ...interface{}) Check {
+	return Check{
+		Name: "migrations",
+		Fn: func(ctx context.Context) error {
+			for _, model := range models {
+				stmt := &gorm.Statement{DB: db}
+				if err := stmt.Parse(model); err != nil {
+					return err
+				}
+
+				var columns []string
+				if err := db.WithContext(ctx).
+					Raw("SELECT column_name FROM information_schema.columns WHERE table_schema = CURRENT_SCHEMA() AND table_name = ?", stmt.Schema.Table).
+					Scan(&columns).Error; err != nil {
+					return err
+				}
+				if len(columns) == 0 {
+					return fmt.Errorf("table %s is not migrated", stmt.Schema.Table)
+				}
+
+				existing := make(map[string]bool, len(columns))
+				for _, column := range columns {
+					existing[column] = true
+				}
+				for _, column := range stmt.Schema.DBNames {
+					if !existing[column] {
+						return fmt.Errorf("column %s.%s is not migrated", stmt.Schema.Table, column)
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func RedisCheck(client *redis.Client) Check {
+	return Check{
+		Name: "redis",
+		Fn: func(ctx context.Context) error {
+			return client.Ping(ctx).Err()
+		},
+	}
+}
@@ -0,0 +1,173 @@
+package health
+
+import (
+	"avito_staj_2025/domain"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func okCheck(name string) Check {
+	return Check{Name: name, Fn: func(ctx context.Context) error { return nil }}
+}
+
+func serve(handler http.HandlerFunc) (*httptest.ResponseRecorder, Response) {
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	var response Response
+	_ = json.Unmarshal(rr.Body.Bytes(), &response)
+	return rr, response
+}
+
+func TestReadiness(t *testing.T) {
+	t.Run("Success - All Checks Pass", func(t *testing.T) {
+		checker := NewChecker(time.Second, okCheck("postgres"), okCheck("redis"))
+
+		rr, response := serve(checker.Readiness)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		assert.Equal(t, StatusOK, response.Status)
+		assert.Len(t, response.Checks, 2)
+		assert.Equal(t, StatusOK, response.Checks["redis"].Status)
+	})
+
+	t.Run("Fail - One Check Fails", func(t *testing.T) {
+		checker := NewChecker(time.Second, okCheck("postgres"), Check{
+			Name: "redis",
+			Fn:   func(ctx context.Context) error { return errors.New("connection refused") },
+		})
+
+		rr, response := serve(checker.Readiness)
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Equal(t, StatusFail, response.Status)
+		assert.Equal(t, StatusOK, response.Checks["postgres"].Status)
+		assert.Equal(t, "connection refused", response.Checks["redis"].Error)
+	})
+
+	t.Run("Fail - Check Exceeds Timeout", func(t *testing.T) {
+		checker := NewChecker(10*time.Millisecond, Check{
+			Name: "postgres",
+			Fn: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		})
+
+		rr, response := serve(checker.Readiness)
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Equal(t, context.DeadlineExceeded.Error(), response.Checks["postgres"].Error)
+		assert.Greater(t, response.Checks["postgres"].LatencyMs, 0.0)
+	})
+
+	t.Run("Fail - Shutting Down", func(t *testing.T) {
+		checker := NewChecker(time.Second, okCheck("postgres"))
+		checker.SetShuttingDown()
+
+		rr, response := serve(checker.Readiness)
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Equal(t, StatusFail, response.Status)
+
+		rr, _ = serve(checker.Liveness)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestLiveness(t *testing.T) {
+	checker := NewChecker(time.Second, Check{
+		Name: "postgres",
+		Fn:   func(ctx context.Context) error { return errors.New("down") },
+	})
+
+	rr, response := serve(checker.Liveness)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, StatusOK, response.Status)
+	assert.Empty(t, response.Checks)
+}
+
+func TestDatabaseChecks(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectPing() // gorm.Open проверяет соединение
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	t.Run("Success - Postgres Ping", func(t *testing.T) {
+		mock.ExpectPing()
+
+		assert.NoError(t, PostgresCheck(gormDB).Fn(ctx))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Fail - Postgres Ping", func(t *testing.T) {
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+		assert.EqualError(t, PostgresCheck(gormDB).Fn(ctx), "connection refused")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Success - Replica Ping", func(t *testing.T) {
+		mock.ExpectPing()
+
+		check := PostgresReplicaCheck(1, gormDB)
+		assert.Equal(t, "postgres_replica_1", check.Name)
+		assert.NoError(t, check.Fn(ctx))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	columnsQuery := regexp.QuoteMeta(`SELECT column_name FROM information_schema.columns WHERE table_schema = CURRENT_SCHEMA() AND table_name = $1`)
+	columnRows := func(columns ...string) *sqlmock.Rows {
+		rows := sqlmock.NewRows([]string{"column_name"})
+		for _, column := range columns {
+			rows.AddRow(column)
+		}
+		return rows
+	}
+
+	t.Run("Success - Tables Migrated", func(t *testing.T) {
+		mock.ExpectQuery(columnsQuery).
+			WithArgs("users").
+			WillReturnRows(columnRows("uuid", "username", "password", "coins", "info_version"))
+		mock.ExpectQuery(columnsQuery).
+			WithArgs("transactions").
+			WillReturnRows(columnRows("uuid", "sender_id", "receiver_id", "amount", "created_at"))
+
+		assert.NoError(t, MigrationsCheck(gormDB, &domain.User{}, &domain.Transaction{}).Fn(ctx))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Fail - Table Missing", func(t *testing.T) {
+		mock.ExpectQuery(columnsQuery).
+			WithArgs("purchases").
+			WillReturnRows(columnRows())
+
+		err := MigrationsCheck(gormDB, &domain.Purchase{}).Fn(ctx)
+		assert.EqualError(t, err, "table purchases is not migrated")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Fail - Column Missing", func(t *testing.T) {
+		mock.ExpectQuery(columnsQuery).
+			WithArgs("users").
+			WillReturnRows(columnRows("uuid", "username", "password", "coins"))
+
+		err := MigrationsCheck(gormDB, &domain.User{}).Fn(ctx)
+		assert.EqualError(t, err, "column users.info_version is not migrated")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
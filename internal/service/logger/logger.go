@@ -1,52 +1,59 @@
 package logger
 
 import (
+	"avito_staj_2025/internal/config"
+	"context"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-var (
-	AccessLogger *zap.Logger
-	DBLogger     *zap.Logger
-)
-
-func InitLoggers() error {
-	accessConfig := zap.NewProductionConfig()
-	accessConfig.OutputPaths = []string{
-		"access.log",
-	}
-	accessConfig.EncoderConfig.TimeKey = "timestamp"
-	accessConfig.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-
-	var err error
-	AccessLogger, err = accessConfig.Build()
+// New собирает логгер с уровнем, форматом и сэмплированием из настроек и заданными выходами
+func New(cfg config.LoggingConfig, outputs []string) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	dbConfig := zap.NewProductionConfig()
-	dbConfig.OutputPaths = []string{
-		"db.log",
+	zapConfig := zap.NewProductionConfig()
+	zapConfig.Level = zap.NewAtomicLevelAt(level)
+	zapConfig.Encoding = cfg.Encoding
+	zapConfig.OutputPaths = outputs
+	zapConfig.EncoderConfig.TimeKey = "timestamp"
+	zapConfig.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	zapConfig.Sampling = nil
+	if cfg.SamplingInitial > 0 {
+		zapConfig.Sampling = &zap.SamplingConfig{
+			Initial:    cfg.SamplingInitial,
+			Thereafter: cfg.SamplingThereafter,
+		}
 	}
-	dbConfig.EncoderConfig.TimeKey = "timestamp"
-	dbConfig.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
-	DBLogger, err = dbConfig.Build()
-	if err != nil {
-		return err
-	}
+	return zapConfig.Build()
+}
+
+type fieldsKey struct{}
 
-	return nil
+// WithFields добавляет в контекст поля, которые попадут во все логи запроса (request_id, user_id)
+func WithFields(ctx context.Context, fields ...zap.Field) context.Context {
+	existing := contextFields(ctx)
+	merged := make([]zap.Field, 0, len(existing)+len(fields))
+	merged = append(merged, existing...)
+	merged = append(merged, fields...)
+	return context.WithValue(ctx, fieldsKey{}, merged)
 }
 
-func SyncLoggers() error {
-	err := AccessLogger.Sync()
-	if err != nil {
-		return err
+// FromContext возвращает логгер компонента с полями запроса из контекста. nil логгер заменяется на пустой
+func FromContext(ctx context.Context, base *zap.Logger) *zap.Logger {
+	if base == nil {
+		base = zap.NewNop()
 	}
-	err = DBLogger.Sync()
-	if err != nil {
-		return err
+	if fields := contextFields(ctx); len(fields) > 0 {
+		return base.With(fields...)
 	}
-	return nil
+	return base
+}
+
+func contextFields(ctx context.Context) []zap.Field {
+	fields, _ := ctx.Value(fieldsKey{}).([]zap.Field)
+	return fields
 }
@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"avito_staj_2025/internal/config"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("Writes To File With Level", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "access.log")
+		cfg := config.Default().Logging
+		cfg.Level = "warn"
+
+		log, err := New(cfg, []string{path})
+		require.NoError(t, err)
+		log.Info("skipped")
+		log.Warn("written")
+		require.NoError(t, log.Sync())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "skipped")
+		assert.Contains(t, string(data), `"msg":"written"`)
+	})
+
+	t.Run("Sampling Drops Repeated Entries", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "access.log")
+		cfg := config.Default().Logging
+		cfg.SamplingInitial, cfg.SamplingThereafter = 2, 1000
+
+		log, err := New(cfg, []string{path})
+		require.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			log.Info("repeated")
+		}
+		require.NoError(t, log.Sync())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, 2, strings.Count(string(data), "repeated"))
+	})
+
+	t.Run("Invalid Level", func(t *testing.T) {
+		cfg := config.Default().Logging
+		cfg.Level = "verbose"
+		_, err := New(cfg, []string{"stdout"})
+		assert.Error(t, err)
+	})
+}
+
+func TestFromContext(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	base := zap.New(core).With(zap.String("component", "repository"))
+
+	ctx := WithFields(context.Background(), zap.String("request_id", "req-1"))
+	ctx = WithFields(ctx, zap.String("user_id", "user-1"))
+	FromContext(ctx, base).Info("bound")
+	FromContext(context.Background(), base).Info("plain")
+
+	entries := logs.TakeAll()
+	require.Len(t, entries, 2)
+	assert.Equal(t, map[string]interface{}{"component": "repository", "request_id": "req-1", "user_id": "user-1"}, entries[0].ContextMap())
+	assert.Equal(t, map[string]interface{}{"component": "repository"}, entries[1].ContextMap())
+
+	assert.NotPanics(t, func() { FromContext(ctx, nil).Info("nop") })
+}
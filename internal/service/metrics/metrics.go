@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "merch"
+
+// Причины неудачной покупки
+const (
+	PurchaseFailedInsufficientFunds = "insufficient_funds"
+	PurchaseFailedOther             = "other"
+)
+
+// Результаты обращения к кэшу для InfoCacheRequests
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+)
+
+// Виды переводов для CoinsTransferred
+const (
+	TransferDirect    = "direct"
+	TransferScheduled = "scheduled"
+	// Отложенный перевод считается, когда получатель его принял
+	TransferPending = "pending"
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route template, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route template and method.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"route", "method"})
+
+	RateLimitRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by the rate limiter.",
+	}, []string{"route"})
+
+	PanicsRecovered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_panics_recovered_total",
+		Help:      "Handler panics recovered by route template.",
+	}, []string{"route"})
+
+	GRPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC calls by full method name and status code.",
+	}, []string{"method", "code"})
+
+	GRPCPanicsRecovered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_panics_recovered_total",
+		Help:      "gRPC handler panics recovered by full method name.",
+	}, []string{"method"})
+
+	InfoCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "info_cache_requests_total",
+		Help:      "User info cache lookups by result: hit, miss or error.",
+	}, []string{"result"})
+
+	CoinsTransferred = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "coins_transferred_total",
+		Help:      "Coins moved between users by completed transfers.",
+	}, []string{"kind"})
+
+	ItemsBought = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "items_bought_total",
+		Help:      "Successful purchases by item.",
+	}, []string{"item"})
+
+	PurchasesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "purchases_failed_total",
+		Help:      "Failed purchases by reason.",
+	}, []string{"reason"})
+)
+
+// ObserveHTTPRequest записывает количество и длительность запроса. route - шаблон маршрута, а не путь, чтобы не плодить метки
+func ObserveHTTPRequest(route string, method string, status int, duration time.Duration) {
+	HTTPRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	HTTPRequestDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// RegisterDBStats публикует статистику пула соединений sql.DB. name попадает в метку db_name
+// и различает основную БД и реплики
+func RegisterDBStats(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
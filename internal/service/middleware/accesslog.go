@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"avito_staj_2025/internal/service/logger"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// AccessLogMiddleware пишет одну запись на запрос: маршрут, пользователь, код ответа, размер тела и длительность.
+// Должен стоять после RequestIDMiddleware, чтобы в записи был request_id
+func AccessLogMiddleware(log *zap.Logger, jwtToken JwtTokenService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := newStatusRecorder(w)
+			next.ServeHTTP(recorder, r)
+
+			fields := []zap.Field{
+				zap.String("method", r.Method),
+				zap.String("route", routeTemplate(r)),
+				zap.String("path", r.URL.Path),
+				zap.Int("status", recorder.status),
+				zap.Int("bytes", recorder.bytes),
+				zap.Duration("duration", time.Since(start)),
+			}
+			if userID := authenticatedUserID(r, jwtToken); userID != "" {
+				fields = append(fields, zap.String("user_id", userID))
+			}
+
+			requestLog := logger.FromContext(r.Context(), log)
+			if recorder.status >= http.StatusInternalServerError {
+				requestLog.Error("Request completed", fields...)
+				return
+			}
+			requestLog.Info("Request completed", fields...)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	jwtToken, err := NewJwtToken("secret-key")
+	require.NoError(t, err)
+	token, err := jwtToken.Create("user-1", time.Now().Add(time.Hour).Unix())
+	require.NoError(t, err)
+
+	core, logs := observer.New(zap.InfoLevel)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/buy/{item}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"errors":"not enough coins"}`))
+	}).Methods("GET")
+	router.HandleFunc("/api/info", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}).Methods("GET")
+	router.Use(RequestIDMiddleware)
+	router.Use(AccessLogMiddleware(zap.New(core), jwtToken))
+
+	t.Run("Error Path With User", func(t *testing.T) {
+		send(router, http.MethodGet, "/api/buy/pen", "192.0.2.1:1234", map[string]string{
+			"JWT-Token":     "Bearer " + token,
+			RequestIDHeader: "req-1",
+		})
+
+		entries := logs.TakeAll()
+		require.Len(t, entries, 1)
+		assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+		fields := entries[0].ContextMap()
+		assert.Equal(t, "/api/buy/{item}", fields["route"])
+		assert.Equal(t, "/api/buy/pen", fields["path"])
+		assert.Equal(t, int64(http.StatusBadRequest), fields["status"])
+		assert.Equal(t, int64(len(`{"errors":"not enough coins"}`)), fields["bytes"])
+		assert.Equal(t, "user-1", fields["user_id"])
+		assert.Equal(t, "req-1", fields["request_id"])
+		assert.Contains(t, fields, "duration")
+	})
+
+	t.Run("Server Error Without Token", func(t *testing.T) {
+		send(router, http.MethodGet, "/api/info", "192.0.2.1:1234", map[string]string{"JWT-Token": "Bearer forged"})
+
+		entries := logs.TakeAll()
+		require.Len(t, entries, 1)
+		assert.Equal(t, zapcore.ErrorLevel, entries[0].Level)
+		assert.NotContains(t, entries[0].ContextMap(), "user_id")
+	})
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"avito_staj_2025/internal/config"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// corsPolicy - разобранная CORSConfig: источники приведены к нижнему регистру, поддомены хранятся отдельно
+type corsPolicy struct {
+	anyOrigin        bool
+	origins          map[string]struct{}
+	subdomains       []subdomainPattern
+	methods          map[string]struct{}
+	headers          map[string]struct{}
+	allowMethods     string
+	allowHeaders     string
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+// subdomainPattern - источник вида https://*.example.com:8443: scheme + "://" и "." + домен с портом
+type subdomainPattern struct {
+	prefix string
+	suffix string
+}
+
+// CORS отвечает на preflight-запросы и добавляет заголовки CORS к ответам для разрешённых источников.
+// Запросы с неразрешённых источников проходят без заголовков CORS, и браузер не отдаст ответ скрипту,
+// а их preflight получает 403
+func CORS(cfg config.CORSConfig) func(http.Handler) http.Handler {
+	policy := newCORSPolicy(cfg)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Ответ зависит от Origin, кэши не должны отдавать его другим источникам
+			w.Header().Add("Vary", "Origin")
+			if !policy.allowOrigin(origin) {
+				if preflight {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if preflight {
+				policy.preflight(w, r, origin)
+				return
+			}
+
+			policy.setOrigin(w, origin)
+			if policy.exposeHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", policy.exposeHeaders)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func newCORSPolicy(cfg config.CORSConfig) *corsPolicy {
+	policy := &corsPolicy{
+		origins:          make(map[string]struct{}),
+		methods:          make(map[string]struct{}),
+		headers:          make(map[string]struct{}),
+		allowMethods:     strings.Join(cfg.AllowedMethods, ", "),
+		allowHeaders:     strings.Join(cfg.AllowedHeaders, ", "),
+		exposeHeaders:    strings.Join(cfg.ExposedHeaders, ", "),
+		allowCredentials: cfg.AllowCredentials,
+		maxAge:           strconv.Itoa(int(cfg.MaxAge.Seconds())),
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(origin)
+		switch {
+		case origin == "*":
+			policy.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			scheme, domain, _ := strings.Cut(origin, "://*.")
+			policy.subdomains = append(policy.subdomains, subdomainPattern{prefix: scheme + "://", suffix: "." + domain})
+		default:
+			policy.origins[origin] = struct{}{}
+		}
+	}
+	for _, method := range cfg.AllowedMethods {
+		policy.methods[strings.ToUpper(method)] = struct{}{}
+	}
+	for _, header := range cfg.AllowedHeaders {
+		policy.headers[http.CanonicalHeaderKey(header)] = struct{}{}
+	}
+	return policy
+}
+
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if _, ok := p.origins[origin]; ok {
+		return true
+	}
+	for _, pattern := range p.subdomains {
+		if strings.HasPrefix(origin, pattern.prefix) && strings.HasSuffix(origin, pattern.suffix) {
+			subdomain := origin[len(pattern.prefix) : len(origin)-len(pattern.suffix)]
+			if validSubdomain(subdomain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validSubdomain не даёт пройти источникам вроде https://evil.com/.example.com или с чужим портом
+func validSubdomain(subdomain string) bool {
+	if subdomain == "" || strings.HasPrefix(subdomain, ".") || strings.HasSuffix(subdomain, ".") {
+		return false
+	}
+	for _, c := range subdomain {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *corsPolicy) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	if _, ok := p.methods[strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))]; !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if _, ok := p.headers[http.CanonicalHeaderKey(header)]; !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+	}
+
+	p.setOrigin(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", p.allowMethods)
+	if p.allowHeaders != "" {
+		w.Header().Set("Access-Control-Allow-Headers", p.allowHeaders)
+	}
+	w.Header().Set("Access-Control-Max-Age", p.maxAge)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setOrigin возвращает конкретный источник, а не *, чтобы работали запросы с учётными данными
+func (p *corsPolicy) setOrigin(w http.ResponseWriter, origin string) {
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if p.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"avito_staj_2025/internal/config"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newCORSHandler(origins ...string) http.Handler {
+	cfg := config.Default().CORS
+	cfg.AllowedOrigins = origins
+	cfg.MaxAge = 5 * time.Minute
+	return CORS(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func corsRequest(handler http.Handler, method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/info", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCORS(t *testing.T) {
+	handler := newCORSHandler("https://app.example.com", "https://*.internal.example.com")
+
+	t.Run("Exact Origin", func(t *testing.T) {
+		rr := corsRequest(handler, http.MethodGet, "https://app.example.com", nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "https://app.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Contains(t, rr.Header().Get("Access-Control-Expose-Headers"), "X-Request-ID")
+		assert.Equal(t, []string{"Origin"}, rr.Header().Values("Vary"))
+	})
+
+	t.Run("Wildcard Subdomain", func(t *testing.T) {
+		for _, origin := range []string{"https://admin.internal.example.com", "https://a.b.internal.example.com"} {
+			rr := corsRequest(handler, http.MethodGet, origin, nil)
+			assert.Equal(t, origin, rr.Header().Get("Access-Control-Allow-Origin"), origin)
+		}
+	})
+
+	t.Run("Rejected Origins", func(t *testing.T) {
+		for _, origin := range []string{
+			"https://evil.com",
+			"http://app.example.com",
+			"https://internal.example.com",
+			"https://evil.com/.internal.example.com",
+			"https://app.example.com.evil.com",
+		} {
+			rr := corsRequest(handler, http.MethodGet, origin, nil)
+			assert.Equal(t, http.StatusOK, rr.Code, origin)
+			assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"), origin)
+		}
+	})
+
+	t.Run("Same Origin Request", func(t *testing.T) {
+		rr := corsRequest(handler, http.MethodGet, "", nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, rr.Header().Get("Vary"))
+	})
+
+	t.Run("Preflight", func(t *testing.T) {
+		rr := corsRequest(handler, http.MethodOptions, "https://app.example.com", map[string]string{
+			"Access-Control-Request-Method":  "POST",
+			"Access-Control-Request-Headers": "content-type, jwt-token",
+		})
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, "https://app.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Contains(t, rr.Header().Get("Access-Control-Allow-Methods"), "POST")
+		assert.Contains(t, rr.Header().Get("Access-Control-Allow-Headers"), "JWT-Token")
+		assert.Equal(t, "300", rr.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("Preflight Rejected", func(t *testing.T) {
+		cases := []struct {
+			origin  string
+			headers map[string]string
+		}{
+			{"https://evil.com", map[string]string{"Access-Control-Request-Method": "POST"}},
+			{"https://app.example.com", map[string]string{"Access-Control-Request-Method": "PATCH"}},
+			{"https://app.example.com", map[string]string{"Access-Control-Request-Method": "POST", "Access-Control-Request-Headers": "X-Debug"}},
+		}
+		for _, tc := range cases {
+			rr := corsRequest(handler, http.MethodOptions, tc.origin, tc.headers)
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+			assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+		}
+	})
+
+	t.Run("Any Origin Without Credentials", func(t *testing.T) {
+		cfg := config.Default().CORS
+		cfg.AllowedOrigins = []string{"*"}
+		cfg.AllowCredentials = false
+		anyOrigin := CORS(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		rr := corsRequest(anyOrigin, http.MethodGet, "https://anyone.dev", nil)
+		assert.Equal(t, "https://anyone.dev", rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("No Origins Configured", func(t *testing.T) {
+		rr := corsRequest(newCORSHandler(), http.MethodGet, "https://app.example.com", nil)
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"net/http"
+)
+
+// queryCanceledCode - SQLSTATE, с которым Postgres прерывает запрос по statement_timeout
+const queryCanceledCode = "57014"
+
+// DeadlineMiddleware задаёт контексту запроса срок из cfg.RouteTimeouts по шаблону маршрута или cfg.RequestTimeout.
+// Обработчики и репозитории получают его через r.Context(). Если срок истёк, а обработчик ничего не ответил, клиент получает 504
+func DeadlineMiddleware(cfg config.ServerConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := cfg.RequestTimeout
+			if routeTimeout, ok := cfg.RouteTimeouts[routeTemplate(r)]; ok {
+				timeout = routeTimeout
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			recorder := newStatusRecorder(w)
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+			if recorder.wroteHeader || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return
+			}
+
+			status, message := TimeoutStatus(ctx, ctx.Err())
+			recorder.Header().Set("Content-Type", "application/json")
+			recorder.WriteHeader(status)
+			_ = json.NewEncoder(recorder).Encode(domain.ErrorResponse{Errors: message, RequestID: GetRequestID(ctx)})
+		})
+	}
+}
+
+// TimeoutStatus возвращает 504, если истёк срок запроса, и 503, если Postgres прервал запрос по statement_timeout.
+// Для остальных ошибок status равен 0
+func TimeoutStatus(ctx context.Context, err error) (status int, message string) {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout, "request deadline exceeded"
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == queryCanceledCode {
+		return http.StatusServiceUnavailable, "database query timed out, retry later"
+	}
+	return 0, ""
+}
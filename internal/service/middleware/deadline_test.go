@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeadlineMiddleware(t *testing.T) {
+	cfg := config.ServerConfig{
+		RequestTimeout: 20 * time.Millisecond,
+		RouteTimeouts:  map[string]time.Duration{"/api/leaderboard": time.Hour},
+	}
+
+	var remaining time.Duration
+	router := mux.NewRouter()
+	router.HandleFunc("/api/info", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}).Methods("GET")
+	router.HandleFunc("/api/leaderboard", func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		require.True(t, ok)
+		remaining = time.Until(deadline)
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+	router.Use(RequestIDMiddleware)
+	router.Use(DeadlineMiddleware(cfg))
+
+	t.Run("Silent Handler Gets 504", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+		req.Header.Set(RequestIDHeader, "req-1")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusGatewayTimeout, rr.Code)
+		var body domain.ErrorResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+		assert.Equal(t, domain.ErrorResponse{Errors: "request deadline exceeded", RequestID: "req-1"}, body)
+	})
+
+	t.Run("Route Timeout", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/leaderboard", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Greater(t, remaining, time.Minute)
+	})
+}
+
+func TestTimeoutStatus(t *testing.T) {
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	statementTimeout := fmt.Errorf("failed to fetch leaderboard: %w", &pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout"})
+
+	cases := []struct {
+		name   string
+		ctx    context.Context
+		err    error
+		status int
+	}{
+		{"Request Deadline", expired, errors.New("failed to fetch user"), http.StatusGatewayTimeout},
+		{"Statement Timeout", context.Background(), statementTimeout, http.StatusServiceUnavailable},
+		{"Other Database Error", context.Background(), &pgconn.PgError{Code: "23505"}, 0},
+		{"Business Error", context.Background(), errors.New("not enough coins"), 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, _ := TimeoutStatus(tc.ctx, tc.err)
+			assert.Equal(t, tc.status, status)
+		})
+	}
+}
@@ -1,58 +1,72 @@
 package middleware
 
 import (
-	"avito_staj_2025/internal/service/dsn"
+	"avito_staj_2025/internal/config"
+	"avito_staj_2025/internal/service/logger"
+	"avito_staj_2025/internal/service/metrics"
+	"avito_staj_2025/internal/service/tracing"
 	"context"
 	"fmt"
+	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
-	"golang.org/x/time/rate"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"log"
 	"net/http"
-	"sync"
+	"regexp"
 	"time"
 )
 
-type contextKey string
-
-const (
-	loggerKey contextKey = "logger"
-)
-
-const requestTimeout = 1000 * time.Second
-
-func WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(ctx, requestTimeout)
-}
-
-func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
-	return context.WithValue(ctx, loggerKey, logger)
-}
-
-func GetLogger(ctx context.Context) (*zap.Logger, error) {
-	logger, ok := ctx.Value(loggerKey).(*zap.Logger)
-	if !ok {
-		return nil, fmt.Errorf("failed to get logger from context")
-	}
-	return logger, nil
-}
-
 type key int
 
 const RequestIDKey key = 0
 
+const RequestIDHeader = "X-Request-ID"
+
+// Допускаем идентификаторы шлюзов и трассировщиков, но не произвольный текст, который попадёт в логи и ответы
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestIDMiddleware берёт X-Request-ID клиента, если он корректен. Иначе используется trace-id из
+// валидного traceparent, а без него - новый UUID. request_id добавляется к полям логов запроса
 func RequestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID := uuid.New().String()
+		requestID := r.Header.Get(RequestIDHeader)
+		if !ValidRequestID(requestID) {
+			requestID = traceIDFromHeader(r.Header)
+		}
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+
 		ctx := context.WithValue(r.Context(), RequestIDKey, requestID)
-		w.Header().Set("X-Request-ID", requestID)
+		ctx = logger.WithFields(ctx, zap.String("request_id", requestID))
+		w.Header().Set(RequestIDHeader, requestID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// ValidRequestID проверяет идентификатор запроса, пришедший от клиента, например из метаданных gRPC
+func ValidRequestID(requestID string) bool {
+	return requestIDPattern.MatchString(requestID)
+}
+
+func traceIDFromHeader(header http.Header) string {
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.HeaderCarrier(header))
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		return spanContext.TraceID().String()
+	}
+	return ""
+}
+
 func GetRequestID(ctx context.Context) string {
 	if reqID, ok := ctx.Value(RequestIDKey).(string); ok {
 		return reqID
@@ -60,60 +74,106 @@ func GetRequestID(ctx context.Context) string {
 	return ""
 }
 
-const (
-	requestsPerSecond = 10000 // Лимит запросов в секунду для каждого IP
-	burstLimit        = 10000 // Максимальный «всплеск» запросов
-)
+// statusRecorder запоминает код ответа и число записанных байт
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
 
-var clientLimiters = sync.Map{}
+func newStatusRecorder(w http.ResponseWriter) *statusRecorder {
+	return &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+}
 
-func getLimiter(ip string) *rate.Limiter {
-	limiter, exists := clientLimiters.Load(ip)
-	if !exists {
-		limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), burstLimit)
-		clientLimiters.Store(ip, limiter)
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
 	}
-	return limiter.(*rate.Limiter)
+	r.status = status
+	r.wroteHeader = true
+	r.ResponseWriter.WriteHeader(status)
 }
 
-func RateLimitMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := r.RemoteAddr
-
-		limiter := getLimiter(ip)
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
 
-		if !limiter.Allow() {
-			http.Error(w, "Too many requests", http.StatusTooManyRequests)
-			return
+// routeTemplate возвращает шаблон маршрута gorilla/mux, например /api/buy/{item}
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
 		}
+	}
+	return "unmatched"
+}
 
-		next.ServeHTTP(w, r)
+// MetricsMiddleware считает запросы и их длительность по шаблонам маршрутов
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := newStatusRecorder(w)
+		next.ServeHTTP(recorder, r)
+		metrics.ObserveHTTPRequest(routeTemplate(r), r.Method, recorder.status, time.Since(start))
 	})
 }
 
-func EnableCORS(next http.Handler) http.Handler {
+// TracingMiddleware продолжает трассу из заголовка traceparent или начинает новую.
+// Должен стоять после RequestIDMiddleware, чтобы связать request_id со спаном
+func TracingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Set-Cookie, X-CSRFToken, x-csrftoken, X-CSRF-Token")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		route := routeTemplate(r)
+		ctx, span := tracing.Tracer().Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+				attribute.String("request_id", GetRequestID(r.Context())),
+			),
+		)
+		defer span.End()
+
+		recorder := newStatusRecorder(w)
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
 		}
-
-		next.ServeHTTP(w, r)
 	})
 }
 
-func DbConnect() *gorm.DB {
-	dsn := dsn.FromEnv()
+func DbConnect(cfg config.DatabaseConfig) *gorm.DB {
+	db := openPostgres(cfg.DSN(), cfg, "database")
+	fmt.Println("Connected to database")
+	return db
+}
 
+// DbConnectReplicas открывает реплики для чтения с теми же настройками пула, что и основная БД
+func DbConnectReplicas(cfg config.DatabaseConfig) []*gorm.DB {
+	replicas := make([]*gorm.DB, 0, len(cfg.Replicas))
+	for i, dsn := range cfg.ReplicaDSNs() {
+		replicas = append(replicas, openPostgres(dsn, cfg, fmt.Sprintf("database replica %d", i)))
+		fmt.Printf("Connected to database replica %d\n", i)
+	}
+	return replicas
+}
+
+func openPostgres(dsn string, cfg config.DatabaseConfig, name string) *gorm.DB {
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
 		PrepareStmt: true, // Включаем подготовку запросов
 	})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatalf("Failed to connect to %s: %v", name, err)
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		log.Fatalf("Failed to register tracing plugin: %v", err)
 	}
 
 	sqlDB, err := db.DB()
@@ -121,25 +181,37 @@ func DbConnect() *gorm.DB {
 		log.Fatalf("Failed to get SQL DB from GORM: %v", err)
 	}
 
-	const maxOpenConns = 1000
-	const maxIdleConns = 500
-	const connMaxLifetime = 30 * time.Minute
-	const connMaxIdleTime = 5 * time.Minute
-
-	sqlDB.SetMaxOpenConns(maxOpenConns)
-	sqlDB.SetMaxIdleConns(maxIdleConns)
-	sqlDB.SetConnMaxLifetime(connMaxLifetime)
-	sqlDB.SetConnMaxIdleTime(connMaxIdleTime)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	// Проверка подключения
 	if err := sqlDB.Ping(); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
+		log.Fatalf("Failed to ping %s: %v", name, err)
 	}
-
-	fmt.Println("Connected to database")
 	return db
 }
 
+// RedisConnect возвращает nil, если Redis не настроен
+func RedisConnect(cfg config.RedisConfig) *redis.Client {
+	if !cfg.Enabled() {
+		return nil
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Address,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		log.Fatalf("Failed to ping redis: %v", err)
+	}
+
+	fmt.Println("Connected to redis")
+	return client
+}
+
 func HashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
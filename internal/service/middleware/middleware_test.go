@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"avito_staj_2025/internal/config"
+	"avito_staj_2025/internal/service/logger"
+	"avito_staj_2025/internal/service/metrics"
+	"avito_staj_2025/internal/service/tracing"
+	"context"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/buy/{item}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}).Methods("GET")
+	router.Use(MetricsMiddleware)
+
+	counter := metrics.HTTPRequests.WithLabelValues("/api/buy/{item}", "GET", "400")
+	before := testutil.ToFloat64(counter)
+
+	for _, item := range []string{"pen", "cup"} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/buy/"+item, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	}
+
+	// Оба пути попадают в один шаблон маршрута
+	assert.Equal(t, before+2, testutil.ToFloat64(counter))
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewTracerProvider(sdktrace.NewSimpleSpanProcessor(exporter), config.Default().Tracing)
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+		_ = provider.Shutdown(context.Background())
+	}()
+
+	var handlerSpan trace.SpanContext
+	router := mux.NewRouter()
+	router.HandleFunc("/api/buy/{item}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusInternalServerError)
+	}).Methods("GET")
+	router.Use(RequestIDMiddleware)
+	router.Use(TracingMiddleware)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/buy/pen", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /api/buy/{item}", span.Name)
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	// Трасса продолжает входящий traceparent
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+	assert.Equal(t, span.SpanContext.SpanID(), handlerSpan.SpanID())
+	assert.Equal(t, codes.Error, span.Status.Code)
+
+	attributes := map[string]string{}
+	for _, attr := range span.Attributes {
+		attributes[string(attr.Key)] = attr.Value.Emit()
+	}
+	assert.Equal(t, rr.Header().Get("X-Request-ID"), attributes["request_id"])
+	assert.Equal(t, "/api/buy/{item}", attributes["http.route"])
+	assert.Equal(t, "500", attributes["http.response.status_code"])
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	var gotID string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = GetRequestID(r.Context())
+		logger.FromContext(r.Context(), zap.New(core)).Info("handled")
+	}))
+
+	serve := func(header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+		for k, v := range header {
+			req.Header.Set(k, v[0])
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Incoming ID Is Kept", func(t *testing.T) {
+		rr := serve(http.Header{RequestIDHeader: {"client-req.42"}})
+		assert.Equal(t, "client-req.42", gotID)
+		assert.Equal(t, "client-req.42", rr.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("Invalid ID Is Replaced", func(t *testing.T) {
+		for _, id := range []string{"bad id\n", strings.Repeat("a", 129)} {
+			rr := serve(http.Header{RequestIDHeader: {id}})
+			assert.NotEqual(t, id, gotID)
+			_, err := uuid.Parse(gotID)
+			assert.NoError(t, err)
+			assert.Equal(t, gotID, rr.Header().Get(RequestIDHeader))
+		}
+	})
+
+	t.Run("Trace ID From Traceparent", func(t *testing.T) {
+		serve(http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}})
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", gotID)
+	})
+
+	t.Run("Generated When Missing", func(t *testing.T) {
+		serve(nil)
+		_, err := uuid.Parse(gotID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Logs Carry Request ID", func(t *testing.T) {
+		logs.TakeAll()
+		serve(http.Header{RequestIDHeader: {"abc"}})
+
+		entries := logs.TakeAll()
+		require.Len(t, entries, 1)
+		assert.Equal(t, "abc", entries[0].ContextMap()["request_id"])
+	})
+}
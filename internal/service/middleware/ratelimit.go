@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/config"
+	"avito_staj_2025/internal/service/logger"
+	"avito_staj_2025/internal/service/metrics"
+	"avito_staj_2025/internal/service/ratelimit"
+	"encoding/json"
+	"go.uber.org/zap"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultRateLimitScope = "default"
+
+// RateLimitMiddleware ограничивает запросы одного клиента. Клиент - пользователь из валидного JWT,
+// иначе IP-адрес с учётом доверенных прокси. Маршруты из cfg.Routes расходуют собственный бюджет,
+// остальные делят общий. Если хранилище лимитера недоступно, запрос пропускается
+func RateLimitMiddleware(cfg config.RateLimitConfig, limiter ratelimit.Limiter, jwtToken JwtTokenService, log *zap.Logger) (func(http.Handler) http.Handler, error) {
+	resolver, err := ratelimit.NewIPResolver(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultBudget := ratelimit.Budget{RequestsPerSecond: cfg.RequestsPerSecond, Burst: cfg.Burst}
+	routeBudgets := make(map[string]ratelimit.Budget, len(cfg.Routes))
+	for route, budget := range cfg.Routes {
+		routeBudgets[route] = ratelimit.Budget{RequestsPerSecond: budget.RequestsPerSecond, Burst: budget.Burst}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeTemplate(r)
+			scope, budget := defaultRateLimitScope, defaultBudget
+			if routeBudget, ok := routeBudgets[route]; ok {
+				scope, budget = route, routeBudget
+			}
+
+			result, err := limiter.Allow(r.Context(), scope+"|"+rateLimitIdentity(r, jwtToken, resolver), budget)
+			if err != nil {
+				logger.FromContext(r.Context(), log).Error("Rate limiter unavailable, request allowed", zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+			setRateLimitHeaders(w, result)
+
+			if !result.Allowed {
+				metrics.RateLimitRejections.WithLabelValues(route).Inc()
+				w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				_ = json.NewEncoder(w).Encode(domain.ErrorResponse{Errors: "Too many requests", RequestID: GetRequestID(r.Context())})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// rateLimitIdentity не доверяет userID без проверки подписи, иначе поддельными токенами можно было бы получать новые бюджеты
+func rateLimitIdentity(r *http.Request, jwtToken JwtTokenService, resolver *ratelimit.IPResolver) string {
+	if userID := authenticatedUserID(r, jwtToken); userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + resolver.ClientIP(r)
+}
+
+// authenticatedUserID возвращает пользователя из заголовка JWT-Token с валидной подписью или пустую строку
+func authenticatedUserID(r *http.Request, jwtToken JwtTokenService) string {
+	tokenString, ok := strings.CutPrefix(r.Header.Get("JWT-Token"), "Bearer ")
+	if !ok || tokenString == "" {
+		return ""
+	}
+	claims, err := jwtToken.Validate(tokenString)
+	if err != nil {
+		return ""
+	}
+	return claims.UserId
+}
+
+// setRateLimitHeaders выставляет заголовки по черновику IETF RateLimit header fields
+func setRateLimitHeaders(w http.ResponseWriter, result ratelimit.Result) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", ceilSeconds(result.ResetAfter))
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"avito_staj_2025/internal/config"
+	"avito_staj_2025/internal/service/ratelimit"
+	"context"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newRateLimitedRouter(t *testing.T, jwtToken JwtTokenService) *mux.Router {
+	cfg := config.RateLimitConfig{
+		RequestsPerSecond: 1,
+		Burst:             3,
+		Routes: map[string]config.RateLimitBudget{
+			"/api/auth": {RequestsPerSecond: 1, Burst: 1},
+		},
+		EntryTTL:       time.Minute,
+		TrustedProxies: []string{"10.0.0.0/8"},
+	}
+	rateLimit, err := RateLimitMiddleware(cfg, ratelimit.NewMemoryLimiter(cfg.EntryTTL), jwtToken, zap.NewNop())
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	router.HandleFunc("/api/auth", ok).Methods("POST")
+	router.HandleFunc("/api/info", ok).Methods("GET")
+	router.HandleFunc("/api/items", ok).Methods("GET")
+	router.Use(rateLimit)
+	return router
+}
+
+func send(router http.Handler, method, path, remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = remoteAddr
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	jwtToken, err := NewJwtToken("secret-key")
+	require.NoError(t, err)
+
+	tokenFor := func(userID string) string {
+		token, err := jwtToken.Create(userID, time.Now().Add(time.Hour).Unix())
+		require.NoError(t, err)
+		return "Bearer " + token
+	}
+
+	t.Run("Success - Headers On Allowed Request", func(t *testing.T) {
+		router := newRateLimitedRouter(t, jwtToken)
+
+		rr := send(router, "GET", "/api/info", "203.0.113.7:5000", nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "3", rr.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "2", rr.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "1", rr.Header().Get("RateLimit-Reset"))
+		assert.Empty(t, rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("Fail - Route Budget Exhausted", func(t *testing.T) {
+		router := newRateLimitedRouter(t, jwtToken)
+
+		assert.Equal(t, http.StatusOK, send(router, "POST", "/api/auth", "203.0.113.7:5000", nil).Code)
+
+		rr := send(router, "POST", "/api/auth", "203.0.113.7:5001", nil)
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+		assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+		assert.JSONEq(t, `{"errors": "Too many requests"}`, rr.Body.String())
+
+		// Общий бюджет не расходуется запросами к /api/auth
+		assert.Equal(t, http.StatusOK, send(router, "GET", "/api/info", "203.0.113.7:5002", nil).Code)
+	})
+
+	t.Run("Success - Default Budget Shared Across Routes", func(t *testing.T) {
+		router := newRateLimitedRouter(t, jwtToken)
+
+		assert.Equal(t, http.StatusOK, send(router, "GET", "/api/info", "203.0.113.7:5000", nil).Code)
+		assert.Equal(t, http.StatusOK, send(router, "GET", "/api/items", "203.0.113.7:5000", nil).Code)
+		assert.Equal(t, http.StatusOK, send(router, "GET", "/api/info", "203.0.113.7:5000", nil).Code)
+		assert.Equal(t, http.StatusTooManyRequests, send(router, "GET", "/api/items", "203.0.113.7:5000", nil).Code)
+	})
+
+	t.Run("Success - Users Behind One Address Limited Separately", func(t *testing.T) {
+		router := newRateLimitedRouter(t, jwtToken)
+		alice := map[string]string{"JWT-Token": tokenFor("alice")}
+		bob := map[string]string{"JWT-Token": tokenFor("bob")}
+
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusOK, send(router, "GET", "/api/info", "203.0.113.7:5000", alice).Code)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, send(router, "GET", "/api/info", "203.0.113.7:5000", alice).Code)
+		assert.Equal(t, http.StatusOK, send(router, "GET", "/api/info", "203.0.113.7:5000", bob).Code)
+	})
+
+	t.Run("Fail - Forged Token Falls Back To Address", func(t *testing.T) {
+		router := newRateLimitedRouter(t, jwtToken)
+		forger, err := NewJwtToken("other-secret")
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			forged, err := forger.Create("forged-user", time.Now().Add(time.Hour).Unix())
+			require.NoError(t, err)
+			headers := map[string]string{"JWT-Token": "Bearer " + forged}
+			assert.Equal(t, http.StatusOK, send(router, "GET", "/api/info", "203.0.113.7:5000", headers).Code)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, send(router, "GET", "/api/info", "203.0.113.7:5000", nil).Code)
+	})
+
+	t.Run("Success - Forwarded Client Behind Trusted Proxy", func(t *testing.T) {
+		router := newRateLimitedRouter(t, jwtToken)
+
+		first := map[string]string{"X-Forwarded-For": "198.51.100.1"}
+		second := map[string]string{"X-Forwarded-For": "198.51.100.2"}
+		assert.Equal(t, http.StatusOK, send(router, "POST", "/api/auth", "10.0.0.5:8080", first).Code)
+		assert.Equal(t, http.StatusTooManyRequests, send(router, "POST", "/api/auth", "10.0.0.5:8080", first).Code)
+		assert.Equal(t, http.StatusOK, send(router, "POST", "/api/auth", "10.0.0.5:8080", second).Code)
+	})
+
+	t.Run("Fail - Invalid Trusted Proxy", func(t *testing.T) {
+		_, err := RateLimitMiddleware(config.RateLimitConfig{TrustedProxies: []string{"not-an-ip"}}, ratelimit.NewMemoryLimiter(time.Minute), jwtToken, zap.NewNop())
+		assert.Error(t, err)
+	})
+}
+
+type unavailableLimiter struct{}
+
+func (unavailableLimiter) Allow(ctx context.Context, key string, budget ratelimit.Budget) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func TestRateLimitMiddlewareFailOpen(t *testing.T) {
+	jwtToken, err := NewJwtToken("secret-key")
+	require.NoError(t, err)
+
+	rateLimit, err := RateLimitMiddleware(config.Default().RateLimit, unavailableLimiter{}, jwtToken, zap.NewNop())
+	require.NoError(t, err)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/info", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }).Methods("GET")
+	router.Use(rateLimit)
+
+	rr := send(router, "GET", "/api/info", "203.0.113.7:5000", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("RateLimit-Limit"))
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/service/logger"
+	"avito_staj_2025/internal/service/metrics"
+	"encoding/json"
+	"go.uber.org/zap"
+	"net/http"
+	"runtime/debug"
+)
+
+// RecoveryMiddleware перехватывает панику обработчика, пишет стек в лог вместе с request_id и отвечает 500
+// в формате ErrorResponse. Должен стоять после RequestIDMiddleware и AccessLogMiddleware, чтобы они увидели код 500
+func RecoveryMiddleware(log *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorder := newStatusRecorder(w)
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				// ErrAbortHandler - штатный способ оборвать ответ, его обрабатывает net/http
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				route := routeTemplate(r)
+				metrics.PanicsRecovered.WithLabelValues(route).Inc()
+				logger.FromContext(r.Context(), log).Error("Handler panic recovered",
+					zap.String("route", route),
+					zap.Any("panic", recovered),
+					zap.ByteString("stack", debug.Stack()),
+				)
+
+				// Часть ответа уже ушла клиенту, дописывать тело ошибки нельзя
+				if recorder.wroteHeader {
+					return
+				}
+				recorder.Header().Set("Content-Type", "application/json")
+				recorder.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(recorder).Encode(domain.ErrorResponse{
+					Errors:    "internal server error",
+					RequestID: GetRequestID(r.Context()),
+				})
+			}()
+			next.ServeHTTP(recorder, r)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/service/metrics"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"testing"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/info", func(w http.ResponseWriter, r *http.Request) {
+		var header string
+		_ = header[len("Bearer "):]
+	}).Methods("GET")
+	router.HandleFunc("/api/buy/{item}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		panic("late failure")
+	}).Methods("GET")
+	router.HandleFunc("/api/abort", func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}).Methods("GET")
+	router.Use(RequestIDMiddleware)
+	router.Use(RecoveryMiddleware(zap.New(core)))
+
+	t.Run("Panic Becomes JSON 500", func(t *testing.T) {
+		counter := metrics.PanicsRecovered.WithLabelValues("/api/info")
+		before := testutil.ToFloat64(counter)
+
+		rr := send(router, http.MethodGet, "/api/info", "192.0.2.1:1234", map[string]string{RequestIDHeader: "req-1"})
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		var body domain.ErrorResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+		assert.Equal(t, domain.ErrorResponse{Errors: "internal server error", RequestID: "req-1"}, body)
+		assert.Equal(t, before+1, testutil.ToFloat64(counter))
+
+		entries := logs.TakeAll()
+		require.Len(t, entries, 1)
+		fields := entries[0].ContextMap()
+		assert.Equal(t, "req-1", fields["request_id"])
+		assert.Equal(t, "/api/info", fields["route"])
+		assert.Contains(t, fields["stack"], "recovery_test.go")
+	})
+
+	t.Run("Panic After Headers Keeps Status", func(t *testing.T) {
+		rr := send(router, http.MethodGet, "/api/buy/pen", "192.0.2.1:1234", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Body.String())
+		assert.Len(t, logs.TakeAll(), 1)
+	})
+
+	t.Run("Abort Handler Is Repanicked", func(t *testing.T) {
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			send(router, http.MethodGet, "/api/abort", "192.0.2.1:1234", nil)
+		})
+		assert.Empty(t, logs.TakeAll())
+	})
+}
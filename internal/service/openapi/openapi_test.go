@@ -0,0 +1,113 @@
+package openapi
+
+import (
+	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/config"
+	"avito_staj_2025/internal/service/binding"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	spec, err := Load()
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	spec.ServeJSON(rr, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var doc map[string]interface{}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+	assert.Contains(t, doc["paths"], "/api/buy/{item}")
+}
+
+func TestValidationMiddleware(t *testing.T) {
+	spec, err := Load()
+	require.NoError(t, err)
+
+	info := `{"coins":100,"inventory":[],"coinHistory":{"received":[],"sent":[]}}`
+	newRouter := func(cfg config.OpenAPIConfig) *mux.Router {
+		router := mux.NewRouter()
+		router.HandleFunc("/api/sendCoin", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+		}).Methods("POST")
+		router.HandleFunc("/api/info", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(info))
+		}).Methods("GET")
+		router.HandleFunc("/api/leaderboard", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}).Methods("GET")
+		router.HandleFunc("/internal", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}).Methods("GET")
+		validate, err := ValidationMiddleware(spec, cfg, zap.NewNop())
+		require.NoError(t, err)
+		router.Use(validate)
+		return router
+	}
+	send := func(router http.Handler, method, path, body string) (*httptest.ResponseRecorder, domain.ErrorResponse) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var errResp domain.ErrorResponse
+		if rr.Code >= http.StatusBadRequest && rr.Body.Len() > 0 {
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&errResp))
+		}
+		return rr, errResp
+	}
+
+	t.Run("Requests", func(t *testing.T) {
+		router := newRouter(config.OpenAPIConfig{ValidateRequests: true})
+
+		rr, _ := send(router, http.MethodPost, "/api/sendCoin", `{"toUser":"bob","amount":5}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr, errResp := send(router, http.MethodPost, "/api/sendCoin", `{"toUser":"bob","amount":0}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, "validation failed", errResp.Errors)
+		require.Len(t, errResp.Fields, 1)
+		assert.Equal(t, "amount", errResp.Fields[0].Field)
+
+		rr, errResp = send(router, http.MethodGet, "/api/leaderboard?window=year", "")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, "invalid request parameter", errResp.Errors)
+		require.Len(t, errResp.Fields, 1)
+		assert.Equal(t, "window", errResp.Fields[0].Field)
+
+		rr, errResp = send(router, http.MethodPost, "/api/sendCoin", `{"toUser":"`+strings.Repeat("a", binding.MaxBodyBytes)+`","amount":5}`)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Contains(t, errResp.Errors, "request body must not exceed")
+
+		rr, _ = send(router, http.MethodGet, "/internal", "")
+		assert.Equal(t, http.StatusTeapot, rr.Code)
+	})
+
+	t.Run("Responses", func(t *testing.T) {
+		router := newRouter(config.OpenAPIConfig{ValidateResponses: true})
+
+		rr, _ := send(router, http.MethodGet, "/api/info", "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, info, rr.Body.String())
+
+		rr, _ = send(router, http.MethodPost, "/api/sendCoin", `{"toUser":"bob","amount":0}`)
+		assert.Equal(t, http.StatusOK, rr.Code, "requests are not checked and empty bodies are allowed")
+
+		info = `{"coins":"100","inventory":[],"coinHistory":{"received":[],"sent":[]}}`
+		rr, errResp := send(router, http.MethodGet, "/api/info", "")
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Contains(t, errResp.Errors, "response does not match API specification")
+	})
+}
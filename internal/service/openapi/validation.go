@@ -0,0 +1,173 @@
+package openapi
+
+import (
+	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/config"
+	"avito_staj_2025/internal/service/binding"
+	"avito_staj_2025/internal/service/logger"
+	"avito_staj_2025/internal/service/middleware"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ValidationMiddleware проверяет запросы и ответы по спецификации в зависимости от cfg.
+// Маршруты вне спецификации пропускаются: их отклоняет основной роутер
+func ValidationMiddleware(spec *Spec, cfg config.OpenAPIConfig, log *zap.Logger) (func(http.Handler) http.Handler, error) {
+	router, err := gorillamux.NewRouter(spec.Doc)
+	if err != nil {
+		return nil, err
+	}
+	options := &openapi3filter.Options{
+		IncludeResponseStatus: true,
+		SkipSettingDefaults:   true,
+		// JWT проверяют обработчики, чтобы ответ 401 не зависел от включённой проверки
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(next http.Handler) http.Handler {
+		if !cfg.ValidateRequests && !cfg.ValidateResponses {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			}
+
+			if cfg.ValidateRequests {
+				// Проверка читает тело целиком до binding.JSON, поэтому предел тела действует уже здесь
+				r.Body = http.MaxBytesReader(w, r.Body, binding.MaxBodyBytes)
+				if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+					var maxBytesErr *http.MaxBytesError
+					if errors.As(err, &maxBytesErr) {
+						writeError(w, r, http.StatusRequestEntityTooLarge, domain.ErrorResponse{
+							Errors: fmt.Sprintf("request body must not exceed %d bytes", maxBytesErr.Limit),
+						})
+						return
+					}
+					writeError(w, r, http.StatusBadRequest, requestErrorResponse(err))
+					return
+				}
+			}
+			if !cfg.ValidateResponses {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			buffer := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(buffer, r)
+			if !buffer.written {
+				return
+			}
+
+			responseOptions := *options
+			// Подтверждения без тела (покупка, перевод) отдаются с Content-Type, но пустыми
+			responseOptions.ExcludeResponseBody = buffer.body.Len() == 0
+			err = openapi3filter.ValidateResponse(r.Context(), &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: input,
+				Status:                 buffer.status,
+				Header:                 w.Header(),
+				Body:                   io.NopCloser(bytes.NewReader(buffer.body.Bytes())),
+				Options:                &responseOptions,
+			})
+			if err != nil {
+				logger.FromContext(r.Context(), log).Error("Response does not match openapi spec",
+					zap.String("path", route.Path),
+					zap.Int("status", buffer.status),
+					zap.Error(err),
+				)
+				w.Header().Del("Content-Length")
+				writeError(w, r, http.StatusInternalServerError, domain.ErrorResponse{
+					Errors: "response does not match API specification: " + err.Error(),
+				})
+				return
+			}
+
+			w.WriteHeader(buffer.status)
+			_, _ = w.Write(buffer.body.Bytes())
+		})
+	}, nil
+}
+
+// requestErrorResponse переводит ошибку проверки запроса в формат ответов binding.JSON
+func requestErrorResponse(err error) domain.ErrorResponse {
+	var requestErr *openapi3filter.RequestError
+	if !errors.As(err, &requestErr) {
+		return domain.ErrorResponse{Errors: "request does not match API specification"}
+	}
+
+	if requestErr.Parameter != nil {
+		return domain.ErrorResponse{
+			Errors: "invalid request parameter",
+			Fields: []domain.FieldError{{Field: requestErr.Parameter.Name, Message: reason(requestErr)}},
+		}
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		// Для лишних полей объекта путь пустой, имя поля есть только в тексте причины
+		if len(schemaErr.JSONPointer()) == 0 {
+			return domain.ErrorResponse{Errors: schemaErr.Reason}
+		}
+		return domain.ErrorResponse{
+			Errors: "validation failed",
+			Fields: []domain.FieldError{{Field: strings.Join(schemaErr.JSONPointer(), "."), Message: schemaErr.Reason}},
+		}
+	}
+	return domain.ErrorResponse{Errors: reason(requestErr)}
+}
+
+func reason(err *openapi3filter.RequestError) string {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err.Err, &schemaErr) {
+		return schemaErr.Reason
+	}
+	if err.Reason != "" {
+		return err.Reason
+	}
+	return err.Error()
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, body domain.ErrorResponse) {
+	body.RequestID = middleware.GetRequestID(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// bufferedResponse придерживает ответ обработчика до проверки. Заголовки пишутся в исходный ResponseWriter
+type bufferedResponse struct {
+	http.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.written {
+		return
+	}
+	b.status = status
+	b.written = true
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.written = true
+	return b.body.Write(p)
+}
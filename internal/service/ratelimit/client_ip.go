@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// IPResolver определяет адрес клиента. X-Forwarded-For учитывается, только если запрос пришёл от доверенного прокси,
+// иначе клиент мог бы подставить любой адрес и обойти лимит
+type IPResolver struct {
+	trusted []netip.Prefix
+}
+
+// NewIPResolver принимает адреса и подсети в CIDR-нотации, например 10.0.0.0/8 или 127.0.0.1
+func NewIPResolver(trustedProxies []string) (*IPResolver, error) {
+	resolver := &IPResolver{}
+	for _, proxy := range trustedProxies {
+		prefix, err := ParseTrustedProxy(proxy)
+		if err != nil {
+			return nil, err
+		}
+		resolver.trusted = append(resolver.trusted, prefix)
+	}
+	return resolver, nil
+}
+
+func ParseTrustedProxy(proxy string) (netip.Prefix, error) {
+	proxy = strings.TrimSpace(proxy)
+	if strings.Contains(proxy, "/") {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(proxy)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// ClientIP возвращает адрес без порта. Цепочка X-Forwarded-For просматривается справа налево
+// до первого адреса, который не принадлежит доверенному прокси
+func (r *IPResolver) ClientIP(req *http.Request) string {
+	remote := remoteAddr(req.RemoteAddr)
+	if !remote.IsValid() {
+		return req.RemoteAddr
+	}
+	if !r.isTrusted(remote) {
+		return remote.String()
+	}
+
+	hops := forwardedFor(req.Header.Values("X-Forwarded-For"))
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(hops[i])
+		if err != nil {
+			break
+		}
+		client = hop.Unmap()
+		if !r.isTrusted(client) {
+			break
+		}
+	}
+	return client.String()
+}
+
+func (r *IPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteAddr(remote string) netip.Addr {
+	host, _, err := net.SplitHostPort(remote)
+	if err != nil {
+		host = remote
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+func forwardedFor(headers []string) []string {
+	var hops []string
+	for _, header := range headers {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	return hops
+}
@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	resolver, err := NewIPResolver([]string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		expectedIP   string
+	}{
+		{"Direct Client Without Port", "203.0.113.7:51234", nil, "203.0.113.7"},
+		{"Untrusted Peer Cannot Spoof", "203.0.113.7:51234", []string{"1.2.3.4"}, "203.0.113.7"},
+		{"Trusted Proxy", "10.0.0.5:8080", []string{"198.51.100.9"}, "198.51.100.9"},
+		{"Chain Of Trusted Proxies", "10.0.0.5:8080", []string{"1.2.3.4, 198.51.100.9, 192.168.1.1"}, "198.51.100.9"},
+		{"Multiple Header Lines", "10.0.0.5:8080", []string{"198.51.100.9", "10.1.1.1"}, "198.51.100.9"},
+		{"All Hops Trusted", "10.0.0.5:8080", []string{"10.2.2.2"}, "10.2.2.2"},
+		{"Malformed Hop Stops Walk", "10.0.0.5:8080", []string{"garbage, 10.3.3.3"}, "10.3.3.3"},
+		{"Trusted Proxy Without Header", "10.0.0.5:8080", nil, "10.0.0.5"},
+		{"IPv6 Client", "[2001:db8::1]:443", nil, "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/info", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			assert.Equal(t, tt.expectedIP, resolver.ClientIP(req))
+		})
+	}
+}
+
+func TestNewIPResolver(t *testing.T) {
+	_, err := NewIPResolver([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+
+	_, err = NewIPResolver([]string{"proxy.local"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"avito_staj_2025/internal/config"
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/time/rate"
+	"math"
+	"sync"
+	"time"
+)
+
+const redisKeyPrefix = "ratelimit:"
+
+// Limiter принимает решение по ключу клиента. Реализации: MemoryLimiter (в памяти процесса)
+// и RedisLimiter (общий для всех реплик)
+type Limiter interface {
+	Allow(ctx context.Context, key string, budget Budget) (Result, error)
+}
+
+// Budget - параметры токен-бакета: скорость пополнения и ёмкость
+type Budget struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// Result описывает решение лимитера и данные для заголовков RateLimit-*
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Через сколько бакет наполнится полностью
+	ResetAfter time.Duration
+	// Через сколько появится следующий токен. Заполняется только при отказе
+	RetryAfter time.Duration
+}
+
+func newResult(budget Budget, allowed bool, tokens float64) Result {
+	if tokens < 0 {
+		tokens = 0
+	}
+	result := Result{
+		Allowed:    allowed,
+		Limit:      budget.Burst,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: secondsToDuration((float64(budget.Burst) - tokens) / budget.RequestsPerSecond),
+	}
+	if !allowed {
+		result.RetryAfter = secondsToDuration((1 - tokens) / budget.RequestsPerSecond)
+	}
+	return result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+type memoryEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// MemoryLimiter хранит бакеты в памяти процесса. Бакеты, к которым не обращались дольше ttl, удаляются,
+// поэтому память ограничена числом активных клиентов, а не всеми, кто когда-либо приходил
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	ttl       time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryLimiter(ttl time.Duration) *MemoryLimiter {
+	return &MemoryLimiter{
+		entries:   make(map[string]*memoryEntry),
+		ttl:       ttl,
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, budget Budget) (Result, error) {
+	now := l.now()
+	limiter := l.limiter(key, budget, now)
+	allowed := limiter.AllowN(now, 1)
+	return newResult(budget, allowed, limiter.TokensAt(now)), nil
+}
+
+// Len возвращает число хранимых бакетов
+func (l *MemoryLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.entries)
+}
+
+func (l *MemoryLimiter) limiter(key string, budget Budget, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Очистка раз в ttl: просроченный бакет всё равно был бы полным, так что удаление не меняет решений
+	if now.Sub(l.lastSweep) >= l.ttl {
+		for entryKey, entry := range l.entries {
+			if now.Sub(entry.lastSeen) >= l.ttl {
+				delete(l.entries, entryKey)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &memoryEntry{limiter: rate.NewLimiter(rate.Limit(budget.RequestsPerSecond), budget.Burst)}
+		l.entries[key] = entry
+	}
+	entry.lastSeen = now
+	return entry.limiter
+}
+
+// New выбирает реализацию по конфигурации. Для redis нужен настроенный клиент
+func New(cfg config.RateLimitConfig, client *redis.Client) (Limiter, error) {
+	switch cfg.Backend {
+	case config.RateLimitBackendRedis:
+		if client == nil {
+			return nil, errors.New("redis rate limiter requires redis.address (REDIS_ADDR)")
+		}
+		return NewRedisLimiter(client, redisKeyPrefix), nil
+	default:
+		return NewMemoryLimiter(cfg.EntryTTL), nil
+	}
+}
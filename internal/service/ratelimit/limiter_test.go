@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(ttl time.Duration) (*MemoryLimiter, *time.Time) {
+	now := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewMemoryLimiter(ttl)
+	limiter.lastSweep = now
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func allow(t *testing.T, limiter Limiter, key string, budget Budget) Result {
+	result, err := limiter.Allow(context.Background(), key, budget)
+	require.NoError(t, err)
+	return result
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	budget := Budget{RequestsPerSecond: 2, Burst: 3}
+
+	t.Run("Success - Burst Then Reject", func(t *testing.T) {
+		limiter, _ := newTestLimiter(time.Minute)
+
+		for i := 2; i >= 0; i-- {
+			result := allow(t, limiter, "client", budget)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, 3, result.Limit)
+			assert.Equal(t, i, result.Remaining)
+		}
+
+		result := allow(t, limiter, "client", budget)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+		assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+		assert.Equal(t, 1500*time.Millisecond, result.ResetAfter)
+	})
+
+	t.Run("Success - Tokens Refill Over Time", func(t *testing.T) {
+		limiter, now := newTestLimiter(time.Minute)
+		for i := 0; i < 3; i++ {
+			allow(t, limiter, "client", budget)
+		}
+		assert.False(t, allow(t, limiter, "client", budget).Allowed)
+
+		*now = now.Add(500 * time.Millisecond)
+		assert.True(t, allow(t, limiter, "client", budget).Allowed)
+	})
+
+	t.Run("Success - Keys Are Independent", func(t *testing.T) {
+		limiter, _ := newTestLimiter(time.Minute)
+		for i := 0; i < 3; i++ {
+			allow(t, limiter, "first", budget)
+		}
+		assert.False(t, allow(t, limiter, "first", budget).Allowed)
+		assert.True(t, allow(t, limiter, "second", budget).Allowed)
+	})
+
+	t.Run("Success - Idle Entries Evicted", func(t *testing.T) {
+		limiter, now := newTestLimiter(time.Minute)
+		allow(t, limiter, "idle", budget)
+		*now = now.Add(30 * time.Second)
+		allow(t, limiter, "active", budget)
+		assert.Equal(t, 2, limiter.Len())
+
+		*now = now.Add(40 * time.Second)
+		allow(t, limiter, "active", budget)
+		assert.Equal(t, 1, limiter.Len())
+	})
+}
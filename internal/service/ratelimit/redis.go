@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+// gcraScript реализует GCRA (generic cell rate algorithm): в ключе хранится только теоретическое время
+// прихода следующего запроса (TAT) в микросекундах. Время берётся с сервера Redis, чтобы расхождение часов
+// между репликами не влияло на решение. Возвращает {allowed, remaining, retry_after_us, reset_after_us}
+var gcraScript = redis.NewScript(`
+local emission = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if not tat or tat < now then
+	tat = now
+end
+
+local new_tat = tat + emission
+local diff = now - (new_tat - emission * burst)
+if diff < 0 then
+	return {0, 0, -diff, tat - now}
+end
+
+redis.call("SET", KEYS[1], new_tat, "PX", math.ceil((new_tat - now) / 1000))
+return {1, math.floor(diff / emission), 0, new_tat - now}
+`)
+
+// RedisLimiter делит бюджет клиента между всеми репликами. Ключ живёт, пока бакет не наполнится, так что
+// отдельная очистка не нужна
+type RedisLimiter struct {
+	client redis.Scripter
+	prefix string
+}
+
+func NewRedisLimiter(client redis.Scripter, prefix string) *RedisLimiter {
+	return &RedisLimiter{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, budget Budget) (Result, error) {
+	emission := int64(float64(time.Second/time.Microsecond) / budget.RequestsPerSecond)
+	if emission < 1 {
+		emission = 1
+	}
+
+	values, err := gcraScript.Run(ctx, l.client, []string{l.prefix + key}, emission, budget.Burst).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("redis rate limiter: %w", err)
+	}
+	if len(values) != 4 {
+		return Result{}, fmt.Errorf("redis rate limiter: unexpected reply %v", values)
+	}
+
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      budget.Burst,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Microsecond,
+		ResetAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"avito_staj_2025/internal/config"
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	server := miniredis.RunT(t)
+	server.SetTime(time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC))
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return server, client
+}
+
+func TestRedisLimiter(t *testing.T) {
+	budget := Budget{RequestsPerSecond: 2, Burst: 3}
+
+	t.Run("Success - Burst Then Reject", func(t *testing.T) {
+		_, client := newTestRedis(t)
+		limiter := NewRedisLimiter(client, "ratelimit:")
+
+		for i := 2; i >= 0; i-- {
+			result := allow(t, limiter, "client", budget)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, 3, result.Limit)
+			assert.Equal(t, i, result.Remaining)
+		}
+
+		result := allow(t, limiter, "client", budget)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+		assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+		assert.Equal(t, 1500*time.Millisecond, result.ResetAfter)
+	})
+
+	t.Run("Success - Tokens Refill Over Time", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		limiter := NewRedisLimiter(client, "ratelimit:")
+		for i := 0; i < 3; i++ {
+			allow(t, limiter, "client", budget)
+		}
+		assert.False(t, allow(t, limiter, "client", budget).Allowed)
+
+		server.SetTime(time.Date(2025, 2, 1, 12, 0, 0, int(500*time.Millisecond), time.UTC))
+		assert.True(t, allow(t, limiter, "client", budget).Allowed)
+	})
+
+	t.Run("Success - Replicas Share Budget", func(t *testing.T) {
+		_, client := newTestRedis(t)
+		first := NewRedisLimiter(client, "ratelimit:")
+		second := NewRedisLimiter(client, "ratelimit:")
+
+		assert.True(t, allow(t, first, "client", budget).Allowed)
+		assert.True(t, allow(t, second, "client", budget).Allowed)
+		assert.True(t, allow(t, first, "client", budget).Allowed)
+		assert.False(t, allow(t, second, "client", budget).Allowed)
+	})
+
+	t.Run("Success - Key Expires When Bucket Is Full", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		limiter := NewRedisLimiter(client, "ratelimit:")
+		allow(t, limiter, "client", budget)
+
+		assert.True(t, server.Exists("ratelimit:client"))
+		assert.Equal(t, 500*time.Millisecond, server.TTL("ratelimit:client"))
+	})
+
+	t.Run("Fail - Redis Unavailable", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		limiter := NewRedisLimiter(client, "ratelimit:")
+		server.Close()
+
+		_, err := limiter.Allow(context.Background(), "client", budget)
+		assert.Error(t, err)
+	})
+}
+
+func TestNew(t *testing.T) {
+	cfg := config.Default().RateLimit
+
+	limiter, err := New(cfg, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &MemoryLimiter{}, limiter)
+
+	cfg.Backend = config.RateLimitBackendRedis
+	_, err = New(cfg, nil)
+	assert.Error(t, err)
+
+	_, client := newTestRedis(t)
+	limiter, err = New(cfg, client)
+	require.NoError(t, err)
+	assert.IsType(t, &RedisLimiter{}, limiter)
+}
@@ -1,12 +1,18 @@
 package router
 
 import (
+	"avito_staj_2025/domain"
 	auth "avito_staj_2025/internal/auth/controller"
 	merch "avito_staj_2025/internal/merch/controller"
+	"avito_staj_2025/internal/service/middleware"
+	"avito_staj_2025/internal/service/openapi"
+	"encoding/json"
 	"github.com/gorilla/mux"
+	"net/http"
+	"strings"
 )
 
-func SetUpRoutes(authHandler *auth.AuthHandler, merchHandler *merch.MerchHandler) *mux.Router {
+func SetUpRoutes(authHandler *auth.AuthHandler, merchHandler *merch.MerchHandler, spec *openapi.Spec) *mux.Router {
 	router := mux.NewRouter()
 	api := "/api"
 
@@ -14,5 +20,41 @@ func SetUpRoutes(authHandler *auth.AuthHandler, merchHandler *merch.MerchHandler
 	router.HandleFunc(api+"/info", merchHandler.GetUserMerchInformation).Methods("GET") // Get user inventory and transactions info
 	router.HandleFunc(api+"/buy/{item}", merchHandler.BuyItem).Methods("GET")           // Buy item by user
 	router.HandleFunc(api+"/sendCoin", merchHandler.SendCoins).Methods("POST")          // Send coins to other user
+	router.HandleFunc(api+"/items", merchHandler.GetCatalog).Methods("GET")             // Get items with current prices
+	router.HandleFunc(api+"/leaderboard", merchHandler.GetLeaderboard).Methods("GET")   // Get top receivers or givers
+
+	router.HandleFunc(api+"/transfers/pending", merchHandler.GetPendingTransfers).Methods("GET")   // Get incoming and outgoing pending transfers
+	router.HandleFunc(api+"/transfers/{id}/accept", merchHandler.AcceptTransfer).Methods("POST")   // Accept pending transfer
+	router.HandleFunc(api+"/transfers/{id}/decline", merchHandler.DeclineTransfer).Methods("POST") // Decline pending transfer
+
+	router.HandleFunc(api+"/transfers/scheduled", merchHandler.ScheduleTransfer).Methods("POST")               // Schedule one-off or recurring transfer
+	router.HandleFunc(api+"/transfers/scheduled", merchHandler.GetScheduledTransfers).Methods("GET")           // Get active scheduled transfers
+	router.HandleFunc(api+"/transfers/scheduled/{id}", merchHandler.CancelScheduledTransfer).Methods("DELETE") // Cancel scheduled transfer
+
+	router.HandleFunc(api+"/openapi.json", spec.ServeJSON).Methods("GET") // Get OpenAPI spec
+	router.HandleFunc(api+"/docs", openapi.ServeDocs).Methods("GET")      // Swagger UI
+
+	// v2: изменяющие запросы только через POST, ответы с ID созданных ресурсов, все ошибки в формате ErrorResponse.
+	// Маршруты выше остаются v1 без изменений
+	v2 := api + "/v2"
+	router.HandleFunc(v2+"/purchases", merchHandler.CreatePurchase).Methods("POST") // Buy item, returns purchase
+	router.HandleFunc(v2+"/transfers", merchHandler.CreateTransfer).Methods("POST") // Send coins, returns transfer ID
+
+	// Ответы 405 и 404 для v2 - отдельные маршруты после основных. NotFoundHandler/MethodNotAllowedHandler
+	// подмаршрутизатора не подходят: gorilla/mux теряет 405, если в подмаршрутизаторе больше одного маршрута
+	router.Handle(v2+"/purchases", errorHandler(http.StatusMethodNotAllowed, "method not allowed", "POST"))
+	router.Handle(v2+"/transfers", errorHandler(http.StatusMethodNotAllowed, "method not allowed", "POST"))
+	router.PathPrefix(v2 + "/").Handler(errorHandler(http.StatusNotFound, "route not found"))
 	return router
 }
+
+func errorHandler(status int, message string, allow ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(allow) > 0 {
+			w.Header().Set("Allow", strings.Join(allow, ", "))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(domain.ErrorResponse{Errors: message, RequestID: middleware.GetRequestID(r.Context())})
+	})
+}
@@ -0,0 +1,86 @@
+package router
+
+import (
+	auth "avito_staj_2025/internal/auth/controller"
+	merch "avito_staj_2025/internal/merch/controller"
+	"avito_staj_2025/internal/service/openapi"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Каждый маршрут роутера описан в openapi.yaml, и в спецификации нет лишних операций
+func TestRoutesMatchOpenAPISpec(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+	router := SetUpRoutes(auth.NewAuthHandler(nil, nil, zap.NewNop()), merch.NewMerchHandler(nil, nil, zap.NewNop()), spec)
+
+	var routes []string
+	err = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Ответы 404 и 405 для v2 регистрируются без методов
+			return nil
+		}
+		for _, method := range methods {
+			routes = append(routes, method+" "+path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	var operations []string
+	for path, item := range spec.Doc.Paths.Map() {
+		for method := range item.Operations() {
+			operations = append(operations, method+" "+path)
+		}
+	}
+
+	sort.Strings(routes)
+	sort.Strings(operations)
+	assert.Equal(t, operations, routes)
+}
+
+func TestV2ErrorEnvelope(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+	router := SetUpRoutes(auth.NewAuthHandler(nil, nil, zap.NewNop()), merch.NewMerchHandler(nil, nil, zap.NewNop()), spec)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{name: "V2 Unknown Route", method: http.MethodPost, path: "/api/v2/unknown", status: http.StatusNotFound, body: `{"errors":"route not found"}`},
+		{name: "V2 Wrong Method", method: http.MethodGet, path: "/api/v2/purchases", status: http.StatusMethodNotAllowed, body: `{"errors":"method not allowed"}`},
+		{name: "V1 Unknown Route Unchanged", method: http.MethodGet, path: "/api/unknown", status: http.StatusNotFound, body: "404 page not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.status, rr.Code)
+			if tt.status == http.StatusMethodNotAllowed {
+				assert.Equal(t, "POST", rr.Header().Get("Allow"))
+			}
+			if strings.HasPrefix(tt.body, "{") {
+				assert.JSONEq(t, tt.body, rr.Body.String())
+			} else {
+				assert.Equal(t, tt.body, strings.TrimSpace(rr.Body.String()))
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package tracing
+
+import (
+	"avito_staj_2025/domain"
+	"avito_staj_2025/internal/config"
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func setupExporter(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewTracerProvider(sdktrace.NewSimpleSpanProcessor(exporter), config.Default().Tracing)
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func attributeValue(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestInit(t *testing.T) {
+	t.Run("Success - Exporter Disabled", func(t *testing.T) {
+		shutdown, err := Init(context.Background(), config.Default().Tracing)
+		require.NoError(t, err)
+		assert.NoError(t, shutdown(context.Background()))
+	})
+}
+
+func TestEnd(t *testing.T) {
+	exporter := setupExporter(t)
+
+	_, span := Start(context.Background(), "operation", attribute.String("user_id", "user123"))
+	End(span, errors.New("not enough coins"))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "operation", spans[0].Name)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "not enough coins", spans[0].Status.Description)
+	assert.Equal(t, "user123", attributeValue(spans[0], "user_id").AsString())
+}
+
+func TestGormPlugin(t *testing.T) {
+	exporter := setupExporter(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gormDB.Use(GormPlugin{}))
+
+	t.Run("Success - Query Span Is Child Of Context Span", func(t *testing.T) {
+		exporter.Reset()
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1`).
+			WithArgs("user123", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "coins"}).AddRow("user123", 1000))
+
+		ctx, parent := Start(context.Background(), "MerchUsecase.GetUserMerchInformation")
+		var user domain.User
+		require.NoError(t, gormDB.WithContext(ctx).Where("uuid = ?", "user123").First(&user).Error)
+		parent.End()
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 2)
+		query := spans[0]
+		assert.Equal(t, "gorm.query", query.Name)
+		assert.Equal(t, spans[1].SpanContext.SpanID(), query.Parent.SpanID())
+		assert.Equal(t, "users", attributeValue(query, "db.collection.name").AsString())
+		assert.Contains(t, attributeValue(query, "db.query.text").AsString(), `SELECT * FROM "users" WHERE uuid = $1`)
+		assert.Equal(t, codes.Unset, query.Status.Code)
+	})
+
+	t.Run("Success - Record Not Found Is Not An Error", func(t *testing.T) {
+		exporter.Reset()
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1`).
+			WithArgs("missing", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid"}))
+
+		var user domain.User
+		err := gormDB.WithContext(context.Background()).Where("uuid = ?", "missing").First(&user).Error
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	})
+
+	t.Run("Fail - Update Error Recorded", func(t *testing.T) {
+		exporter.Reset()
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "users" SET "coins"=\$1 WHERE uuid = \$2`).
+			WithArgs(10, "user123").
+			WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		err := gormDB.WithContext(context.Background()).Model(&domain.User{}).Where("uuid = ?", "user123").Update("coins", 10).Error
+		assert.Error(t, err)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "gorm.update", spans[0].Name)
+		assert.Equal(t, codes.Error, spans[0].Status.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,79 @@
+package validation
+
+import (
+	"avito_staj_2025/domain"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Struct проверяет поля структуры по тегу validate. Поддерживаются правила required, min=N и max=N:
+// для строк это длина в символах, для чисел - значение
+func Struct(v interface{}) []domain.FieldError {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs []domain.FieldError
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		for _, rule := range strings.Split(tag, ",") {
+			if message := checkRule(value.Field(i), rule); message != "" {
+				errs = append(errs, domain.FieldError{Field: jsonName(field), Message: message})
+				break
+			}
+		}
+	}
+	return errs
+}
+
+func checkRule(value reflect.Value, rule string) string {
+	name, arg, _ := strings.Cut(rule, "=")
+	switch name {
+	case "required":
+		if value.IsZero() {
+			return "is required"
+		}
+		return ""
+	case "min", "max":
+		limit, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validation: bad %s rule %q", name, rule))
+		}
+		actual, unit := measure(value)
+		if name == "min" && actual < limit {
+			return fmt.Sprintf("must be at least %d%s", limit, unit)
+		}
+		if name == "max" && actual > limit {
+			return fmt.Sprintf("must be at most %d%s", limit, unit)
+		}
+		return ""
+	default:
+		panic(fmt.Sprintf("validation: unknown rule %q", rule))
+	}
+}
+
+func measure(value reflect.Value) (int64, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return int64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int(), ""
+	default:
+		panic(fmt.Sprintf("validation: min/max not supported for %s", value.Kind()))
+	}
+}
+
+func jsonName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}